@@ -0,0 +1,120 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package appconfig manages the agent's on-disk configuration file.
+package appconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// DefaultProgramFolder is where the agent keeps its program files, including the inventory
+	// policy document dropped by the console/CLI.
+	DefaultProgramFolder = "/etc/amazon/ssm"
+
+	// DefaultDataStorePath is the root of the agent's runtime data store.
+	DefaultDataStorePath = "/var/lib/amazon/ssm"
+
+	// DefaultDocumentRootDirName is the directory, under an instance's data store, that holds
+	// orchestration state for the documents it has run.
+	DefaultDocumentRootDirName = "document"
+
+	// appConfigFileName is the config file read from DefaultProgramFolder.
+	appConfigFileName = "amazon-ssm-agent.json"
+)
+
+// AgentInfo holds the agent-wide settings in the [Agent] section of the config file.
+type AgentInfo struct {
+	Region               string
+	OrchestrationRootDir string
+}
+
+// SsmCfg holds the SSM-service-specific settings in the [Ssm] section of the config file.
+type SsmCfg struct {
+	Endpoint               string
+	InventoryPlugin        string
+	HealthFrequencyMinutes int
+
+	// CustomInventoryDir, when set, is scanned for custom inventory gatherer .so plugins - see
+	// gatherers.LoadGatherers and gatherers.Rescan.
+	CustomInventoryDir string
+
+	// InventoryPolicyBucket and InventoryPolicyKey, when set, add an S3 inventory policy source
+	// alongside the local file policy - see policysource.NewS3PolicySource.
+	InventoryPolicyBucket string
+	InventoryPolicyKey    string
+
+	// InventoryLargePayloadBucket, when set, is where inventory items that breach the per-type size
+	// limit are spilled instead of being dropped - see datauploader.NewS3LargePayloadStore.
+	InventoryLargePayloadBucket        string
+	InventoryLargePayloadPrefix        string
+	InventoryLargePayloadKmsKeyId      string
+	InventoryLargePayloadRetentionDays int
+
+	// InventoryMaxConcurrency bounds how many gatherers Execute runs at once; 0 means the plugin's
+	// built-in default applies.
+	InventoryMaxConcurrency int
+}
+
+// SsmagentConfig is the agent's full runtime configuration, as returned by Config.
+type SsmagentConfig struct {
+	Agent AgentInfo
+	Ssm   SsmCfg
+}
+
+// DefaultConfig returns the configuration used when the config file is absent or doesn't override
+// a given value.
+func DefaultConfig() SsmagentConfig {
+	return SsmagentConfig{
+		Ssm: SsmCfg{
+			HealthFrequencyMinutes: 5,
+		},
+	}
+}
+
+var (
+	cachedConfig     SsmagentConfig
+	haveCachedConfig bool
+	configLock       sync.RWMutex
+)
+
+// Config returns the agent's runtime configuration. It is read from DefaultProgramFolder once and
+// cached; pass forceUpdate to re-read the file from disk.
+func Config(forceUpdate bool) (SsmagentConfig, error) {
+	configLock.RLock()
+	if haveCachedConfig && !forceUpdate {
+		defer configLock.RUnlock()
+		return cachedConfig, nil
+	}
+	configLock.RUnlock()
+
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	config := DefaultConfig()
+
+	data, err := ioutil.ReadFile(filepath.Join(DefaultProgramFolder, appConfigFileName))
+	if err == nil {
+		if err = json.Unmarshal(data, &config); err != nil {
+			return SsmagentConfig{}, err
+		}
+	}
+
+	cachedConfig = config
+	haveCachedConfig = true
+	return cachedConfig, nil
+}
@@ -64,12 +64,24 @@ func ParseDocumentWithParams(log log.T,
 
 	payload.Parameters = parseParameters(log, rawData.Association.Parameters, payload.DocumentContent.Parameters)
 
+	documentID := *rawData.Association.AssociationId
+	var secrets []string
+	if payload.Parameters, secrets, err = resolveReferences(log, documentID, payload.Parameters); err != nil {
+		return nil, err
+	}
+
+	var pluginSecrets []string
+	if pluginSecrets, err = resolvePluginProperties(log, documentID, payload); err != nil {
+		return nil, err
+	}
+	secrets = append(secrets, pluginSecrets...)
+
 	var parametersContent string
 	if parametersContent, err = jsonutil.Marshal(payload.Parameters); err != nil {
 		log.Error("Could not marshal parameters ", err)
 		return nil, err
 	}
-	log.Debug("After marshal parameters ", jsonutil.Indent(parametersContent))
+	log.Debug("After marshal parameters ", redactSecrets(jsonutil.Indent(parametersContent), secrets))
 
 	validParams := parameters.ValidParameters(log, payload.Parameters)
 	// add default values for missing parameters
@@ -146,6 +158,18 @@ func parseParameters(log log.T, params map[string][]*string, paramsDef map[strin
 				result[name] = param[0]
 			case contracts.ParamTypeStringList:
 				result[name] = param
+			case contracts.ParamTypeSecureString:
+				// param[0] is ordinarily the SSM Parameter Store name the value should be decrypted
+				// from, which we wrap as an ssm-secure reference so resolveReferences resolves it
+				// the same way as a {{ssm-secure:...}} reference written directly into a document.
+				// It can also already arrive pre-wrapped as {{ssm-secure:...}} (or {{ssm:...}}) -
+				// don't wrap it again in that case, or the doubled braces won't match
+				// ssmReferencePattern and the secret would ship unresolved.
+				value := *param[0]
+				if !ssmReferencePattern.MatchString(value) {
+					value = fmt.Sprintf("{{ssm-secure:%v}}", value)
+				}
+				result[name] = &value
 			default:
 				log.Debug("unknown parameter type ", definition.ParamType)
 			}
@@ -154,6 +178,84 @@ func parseParameters(log log.T, params map[string][]*string, paramsDef map[strin
 	return result
 }
 
+// resolveReferences resolves every {{ssm:...}}/{{ssm-secure:...}} reference found in params against
+// SSM Parameter Store, returning the secret values it resolved so the caller can redact them from
+// debug logging. If params has no such reference, it returns immediately without ever building a
+// resolver, so a document that only uses plain String/StringList parameters works regardless of
+// whether SSM parameter resolution is configured correctly. If the resolver cannot be configured, it
+// returns a *ResolutionError rather than shipping the unresolved {{ssm:...}}/{{ssm-secure:...}}
+// placeholders as if they were real values.
+func resolveReferences(log log.T, documentID string, params map[string]interface{}) (map[string]interface{}, []string, error) {
+	names := make(map[string]bool)
+	collectReferences(params, names)
+	if len(names) == 0 {
+		return params, nil, nil
+	}
+
+	resolver, err := getParameterResolver()
+	if err != nil {
+		log.Error("Could not configure SSM parameter resolver ", err)
+		return nil, nil, &ResolutionError{Err: err}
+	}
+
+	resolved, secrets, err := resolver.Resolve(log, documentID, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolvedParams, _ := resolved.(map[string]interface{})
+	return resolvedParams, secrets, nil
+}
+
+// resolvePluginProperties resolves {{ssm:...}}/{{ssm-secure:...}} references written directly into a
+// plugin's properties or inputs, rather than routed through a document Parameter - these never pass
+// through parseParameters, so they'd otherwise reach the plugin unresolved. If no step or runtime
+// config has such a reference, it returns immediately without ever building a resolver. If the
+// resolver cannot be configured, it returns a *ResolutionError rather than shipping the properties
+// unresolved.
+func resolvePluginProperties(log log.T, documentID string, payload *messageContracts.SendCommandPayload) ([]string, error) {
+	names := make(map[string]bool)
+	for _, config := range payload.DocumentContent.RuntimeConfig {
+		collectReferences(config.Properties, names)
+	}
+	for _, step := range payload.DocumentContent.MainSteps {
+		collectReferences(step.Inputs, names)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	resolver, err := getParameterResolver()
+	if err != nil {
+		log.Error("Could not configure SSM parameter resolver ", err)
+		return nil, &ResolutionError{Err: err}
+	}
+
+	var secrets []string
+
+	for name, config := range payload.DocumentContent.RuntimeConfig {
+		resolved, found, err := resolver.Resolve(log, documentID, config.Properties)
+		if err != nil {
+			return nil, err
+		}
+		config.Properties = resolved
+		secrets = append(secrets, found...)
+		payload.DocumentContent.RuntimeConfig[name] = config
+	}
+
+	for i, step := range payload.DocumentContent.MainSteps {
+		resolved, found, err := resolver.Resolve(log, documentID, step.Inputs)
+		if err != nil {
+			return nil, err
+		}
+		step.Inputs = resolved
+		secrets = append(secrets, found...)
+		payload.DocumentContent.MainSteps[i] = step
+	}
+
+	return secrets, nil
+}
+
 // buildPluginsInfo builds the PluginsInfo for document state
 func buildPluginsInfo(
 	payload *messageContracts.SendCommandPayload,
@@ -226,4 +328,4 @@ func buildPluginsInfo(
 	}
 
 	return
-}
\ No newline at end of file
+}
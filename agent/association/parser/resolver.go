@@ -0,0 +1,279 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// parameterCacheTTL bounds how long a resolved parameter value is reused across plugins within the
+// same association document, so a document with many plugins referencing the same parameter doesn't
+// make one GetParameters call per plugin.
+const parameterCacheTTL = 5 * time.Minute
+
+// getParametersBatchLimit is the largest number of names GetParameters accepts in a single call.
+const getParametersBatchLimit = 10
+
+// ssmReferencePattern matches {{ssm:name}} and {{ssm-secure:name}} references - these show up both as
+// the value of a SecureString parameter (see parseParameters) and directly inside plugin properties
+// or inputs that were never routed through a document parameter at all. ssm-secure is listed first so
+// it wins the alternation over its ssm prefix.
+var ssmReferencePattern = regexp.MustCompile(`{{\s*(ssm-secure|ssm):([^{}]+?)\s*}}`)
+
+// ResolutionError indicates a {{ssm:...}}/{{ssm-secure:...}} reference could not be resolved against
+// SSM Parameter Store. Association bookkeeping should mark the run failed with this as the reason,
+// rather than falling through to the parameter's default value as if it had simply been omitted.
+type ResolutionError struct {
+	Name string
+	Err  error
+}
+
+func (e *ResolutionError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("failed to resolve SSM parameter references - %v", e.Err)
+	}
+	return fmt.Sprintf("failed to resolve SSM parameter(s) %v - %v", e.Name, e.Err)
+}
+
+// ParameterResolver resolves {{ssm:name}}/{{ssm-secure:name}} references against SSM Parameter Store.
+type ParameterResolver interface {
+	// Resolve walks value - a string, or any combination of map[string]interface{}, []interface{},
+	// *string and []*string - and returns a copy with every reference substituted for its value.
+	// documentID scopes the resolver's cache, so repeated calls for the same document (one per
+	// plugin, typically) don't each make their own GetParameters call. secrets holds every value
+	// resolved from a {{ssm-secure:...}} reference, so the caller can redact them from debug logs.
+	Resolve(log log.T, documentID string, value interface{}) (resolved interface{}, secrets []string, err error)
+}
+
+// cachedParameter is one resolved parameter value, valid until expires.
+type cachedParameter struct {
+	value   string
+	expires time.Time
+}
+
+// ssmParameterResolver implements ParameterResolver against the SSM GetParameters API.
+type ssmParameterResolver struct {
+	ssm *ssm.SSM
+
+	mu    sync.Mutex
+	cache map[string]cachedParameter // keyed by documentID + "/" + parameter name
+}
+
+// NewSSMParameterResolver creates a ParameterResolver backed by ssmClient.
+func NewSSMParameterResolver(ssmClient *ssm.SSM) ParameterResolver {
+	return &ssmParameterResolver{ssm: ssmClient, cache: make(map[string]cachedParameter)}
+}
+
+// Resolve implements ParameterResolver.
+func (r *ssmParameterResolver) Resolve(log log.T, documentID string, value interface{}) (interface{}, []string, error) {
+	names := make(map[string]bool)
+	collectReferences(value, names)
+	if len(names) == 0 {
+		return value, nil, nil
+	}
+
+	resolved, err := r.resolveNames(log, documentID, names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var secrets []string
+	for name, secure := range names {
+		if secure {
+			secrets = append(secrets, resolved[name])
+		}
+	}
+
+	return substituteReferences(value, resolved), secrets, nil
+}
+
+// resolveNames fetches every name not already cached for documentID, batching GetParameters calls to
+// respect its 10-name limit, and returns the resolved value of every requested name.
+func (r *ssmParameterResolver) resolveNames(log log.T, documentID string, names map[string]bool) (map[string]string, error) {
+	result := make(map[string]string, len(names))
+	now := time.Now()
+
+	var toFetch []string
+	r.mu.Lock()
+	for name := range names {
+		if cached, ok := r.cache[documentID+"/"+name]; ok && cached.expires.After(now) {
+			result[name] = cached.value
+			continue
+		}
+		toFetch = append(toFetch, name)
+	}
+	r.mu.Unlock()
+
+	for i := 0; i < len(toFetch); i += getParametersBatchLimit {
+		end := i + getParametersBatchLimit
+		if end > len(toFetch) {
+			end = len(toFetch)
+		}
+		batch := toFetch[i:end]
+
+		output, err := r.ssm.GetParameters(&ssm.GetParametersInput{
+			Names:          aws.StringSlice(batch),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, &ResolutionError{Name: strings.Join(batch, ", "), Err: err}
+		}
+		if len(output.InvalidParameters) > 0 {
+			return nil, &ResolutionError{
+				Name: strings.Join(aws.StringValueSlice(output.InvalidParameters), ", "),
+				Err:  fmt.Errorf("parameter does not exist in SSM Parameter Store"),
+			}
+		}
+
+		r.mu.Lock()
+		for _, parameter := range output.Parameters {
+			name := aws.StringValue(parameter.Name)
+			value := aws.StringValue(parameter.Value)
+			result[name] = value
+			r.cache[documentID+"/"+name] = cachedParameter{value: value, expires: now.Add(parameterCacheTTL)}
+		}
+		r.mu.Unlock()
+
+		log.Debugf("Resolved %v SSM parameter(s) for association document %v", len(batch), documentID)
+	}
+
+	return result, nil
+}
+
+// collectReferences walks value, recording every {{ssm:name}}/{{ssm-secure:name}} reference it finds
+// into names. A name already recorded as secure stays secure even if a later, non-secure reference to
+// the same name is also found.
+func collectReferences(value interface{}, names map[string]bool) {
+	switch v := value.(type) {
+	case string:
+		for _, match := range ssmReferencePattern.FindAllStringSubmatch(v, -1) {
+			secure := strings.EqualFold(match[1], "ssm-secure")
+			names[match[2]] = names[match[2]] || secure
+		}
+	case *string:
+		if v != nil {
+			collectReferences(*v, names)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectReferences(item, names)
+		}
+	case []*string:
+		for _, item := range v {
+			collectReferences(item, names)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			collectReferences(item, names)
+		}
+	}
+}
+
+// substituteReferences returns a copy of value with every {{ssm:name}}/{{ssm-secure:name}} reference
+// that has an entry in resolved replaced by its value. References with no entry in resolved (there
+// shouldn't be any, since resolveNames errors out rather than returning a partial result) are left as
+// they are.
+func substituteReferences(value interface{}, resolved map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return ssmReferencePattern.ReplaceAllStringFunc(v, func(match string) string {
+			groups := ssmReferencePattern.FindStringSubmatch(match)
+			if replacement, ok := resolved[groups[2]]; ok {
+				return replacement
+			}
+			return match
+		})
+	case *string:
+		if v == nil {
+			return v
+		}
+		replaced := substituteReferences(*v, resolved).(string)
+		return &replaced
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = substituteReferences(item, resolved)
+		}
+		return out
+	case []*string:
+		out := make([]*string, len(v))
+		for i, item := range v {
+			out[i] = substituteReferences(item, resolved).(*string)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = substituteReferences(item, resolved)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// redactSecrets replaces every occurrence of a resolved secret value in content with a placeholder,
+// so debug logging of a document or its parameters never prints a decrypted SecureString value.
+func redactSecrets(content string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		content = strings.Replace(content, secret, "<redacted>", -1)
+	}
+	return content
+}
+
+var (
+	defaultResolverMu sync.Mutex
+	defaultResolver   ParameterResolver
+)
+
+// getParameterResolver lazily builds the package-wide ParameterResolver from agent appconfig, the
+// same way other plugins (e.g. the inventory uploader) construct their own SSM client on demand
+// rather than having one threaded through every call site. A failed build (e.g. a malformed
+// amazon-ssm-agent.json) is not cached - the next document that actually needs the resolver tries
+// building it again, rather than every document for the rest of the process's life being stuck with
+// the first failure.
+func getParameterResolver() (ParameterResolver, error) {
+	defaultResolverMu.Lock()
+	defer defaultResolverMu.Unlock()
+
+	if defaultResolver != nil {
+		return defaultResolver, nil
+	}
+
+	appCfg, err := appconfig.Config(false)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := sdkutil.AwsConfig()
+	cfg.Region = &appCfg.Agent.Region
+	cfg.Endpoint = &appCfg.Ssm.Endpoint
+
+	defaultResolver = NewSSMParameterResolver(ssm.New(session.New(cfg)))
+	return defaultResolver, nil
+}
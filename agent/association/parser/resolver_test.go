@@ -0,0 +1,128 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectReferencesFindsSsmAndSsmSecureAcrossNestedTypes(t *testing.T) {
+	s := func(v string) *string { return &v }
+
+	value := map[string]interface{}{
+		"plain":     "no reference here",
+		"str":       "{{ssm:plainName}}",
+		"secure":    "{{ssm-secure:secretName}}",
+		"ptr":       s("{{ssm:ptrName}}"),
+		"list":      []interface{}{"{{ssm:listName}}"},
+		"ptrlist":   []*string{s("{{ssm-secure:ptrListName}}")},
+		"untouched": 42,
+	}
+
+	names := make(map[string]bool)
+	collectReferences(value, names)
+
+	assert.Equal(t, map[string]bool{
+		"plainName":   false,
+		"secretName":  true,
+		"ptrName":     false,
+		"listName":    false,
+		"ptrListName": true,
+	}, names)
+}
+
+func TestCollectReferencesKeepsNameSecureOnceFoundSecure(t *testing.T) {
+	// "shared" is referenced once as ssm-secure and once as plain ssm - it must stay secure so the
+	// resolved value still gets redacted from logs.
+	value := []interface{}{"{{ssm-secure:shared}}", "{{ssm:shared}}"}
+
+	names := make(map[string]bool)
+	collectReferences(value, names)
+
+	assert.Equal(t, map[string]bool{"shared": true}, names)
+}
+
+func TestSubstituteReferencesReplacesKnownNamesAndLeavesUnknownAlone(t *testing.T) {
+	s := func(v string) *string { return &v }
+	resolved := map[string]string{"known": "resolved-value"}
+
+	value := map[string]interface{}{
+		"hit":  "prefix {{ssm:known}} suffix",
+		"miss": "{{ssm:missing}}",
+		"ptr":  s("{{ssm-secure:known}}"),
+		"list": []interface{}{"{{ssm:known}}"},
+	}
+
+	out := substituteReferences(value, resolved).(map[string]interface{})
+
+	assert.Equal(t, "prefix resolved-value suffix", out["hit"])
+	assert.Equal(t, "{{ssm:missing}}", out["miss"])
+	assert.Equal(t, "resolved-value", *out["ptr"].(*string))
+	assert.Equal(t, []interface{}{"resolved-value"}, out["list"])
+}
+
+func TestRedactSecretsReplacesEveryOccurrenceAndSkipsEmptyValues(t *testing.T) {
+	content := "token=shh and again shh, but not \"\""
+	redacted := redactSecrets(content, []string{"shh", ""})
+
+	assert.Equal(t, "token=<redacted> and again <redacted>, but not \"\"", redacted)
+}
+
+func TestResolutionErrorErrorIncludesNameWhenSet(t *testing.T) {
+	err := &ResolutionError{Name: "myParam", Err: errors.New("boom")}
+	assert.Equal(t, "failed to resolve SSM parameter(s) myParam - boom", err.Error())
+}
+
+func TestResolutionErrorErrorOmitsNameWhenUnset(t *testing.T) {
+	err := &ResolutionError{Err: errors.New("boom")}
+	assert.Equal(t, "failed to resolve SSM parameter references - boom", err.Error())
+}
+
+func TestParseParametersWrapsSecureStringNameAsSsmSecureReference(t *testing.T) {
+	name := "myParameterName"
+	params := map[string][]*string{"secret": {&name}}
+	paramsDef := map[string]*contracts.Parameter{
+		"secret": {ParamType: contracts.ParamTypeSecureString},
+	}
+
+	result := parseParameters(log.NewMockLog(), params, paramsDef)
+
+	value, ok := result["secret"].(*string)
+	assert.True(t, ok)
+	assert.Equal(t, fmt.Sprintf("{{ssm-secure:%v}}", name), *value)
+}
+
+func TestParseParametersDoesNotDoubleWrapAnAlreadyWrappedSecureStringReference(t *testing.T) {
+	// If the value arrives pre-wrapped (e.g. "{{ssm-secure:myParameterName}}" rather than a bare
+	// name), wrapping it again would produce "{{ssm-secure:{{ssm-secure:myParameterName}}}}", whose
+	// doubled braces never match ssmReferencePattern - shipping the secret unresolved.
+	alreadyWrapped := "{{ssm-secure:myParameterName}}"
+	params := map[string][]*string{"secret": {&alreadyWrapped}}
+	paramsDef := map[string]*contracts.Parameter{
+		"secret": {ParamType: contracts.ParamTypeSecureString},
+	}
+
+	result := parseParameters(log.NewMockLog(), params, paramsDef)
+
+	value, ok := result["secret"].(*string)
+	assert.True(t, ok)
+	assert.Equal(t, alreadyWrapped, *value)
+	assert.True(t, ssmReferencePattern.MatchString(*value))
+}
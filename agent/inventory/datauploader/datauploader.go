@@ -0,0 +1,166 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package datauploader is responsible for converting gathered inventory data into the format
+// expected by the SSM PutInventory API and uploading it.
+package datauploader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/times"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// T is the interface for converting inventory.Item into SSM's wire format and uploading it.
+type T interface {
+	// ConvertToSsmInventoryItems transforms the gatherer output into the shape PutInventory expects.
+	ConvertToSsmInventoryItems(context context.T, items []inventory.Item) (inventoryMessage []*ssm.InventoryItem, err error)
+
+	// SendDataToSSM uploads inventoryItems to the SSM Inventory service.
+	SendDataToSSM(context context.T, inventoryItems []*ssm.InventoryItem)
+
+	// UploadLargePayload spills an inventory item that is too large to send to SSM directly to the
+	// configured LargePayloadStore and returns a reference to it.
+	UploadLargePayload(context context.T, item inventory.Item) (LargePayloadRef, error)
+}
+
+// InventoryUploader implements T using the SSM PutInventory API.
+type InventoryUploader struct {
+	ssm           *ssm.SSM
+	largePayloads LargePayloadStore
+}
+
+// NewInventoryUploader creates a new InventoryUploader. largePayloads is optional - pass nil to
+// disable the large payload spill path, in which case UploadLargePayload always errors.
+func NewInventoryUploader(context context.T, largePayloads LargePayloadStore) (*InventoryUploader, error) {
+	appCfg, err := appconfig.Config(false)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := sdkutil.AwsConfig()
+	cfg.Region = &appCfg.Agent.Region
+	cfg.Endpoint = &appCfg.Ssm.Endpoint
+
+	return &InventoryUploader{ssm: ssm.New(session.New(cfg)), largePayloads: largePayloads}, nil
+}
+
+// ConvertToSsmInventoryItems transforms every inventory.Item into an ssm.InventoryItem, JSON
+// encoding its content the way PutInventory expects.
+func (u *InventoryUploader) ConvertToSsmInventoryItems(context context.T, items []inventory.Item) (inventoryMessage []*ssm.InventoryItem, err error) {
+	log := context.Log()
+
+	for _, item := range items {
+		var content string
+		if content, err = jsonutil.Marshal(item.Content); err != nil {
+			log.Errorf("Unable to convert inventory item %v to SSM format - %v", item.Name, err.Error())
+			return nil, err
+		}
+
+		inventoryMessage = append(inventoryMessage, &ssm.InventoryItem{
+			TypeName:      aws.String(item.Name),
+			SchemaVersion: aws.String(item.SchemaVersion),
+			CaptureTime:   aws.String(item.CaptureTime),
+			Content:       []*string{aws.String(content)},
+		})
+	}
+
+	return inventoryMessage, nil
+}
+
+// SendDataToSSM uploads inventoryItems via PutInventory, logging (rather than returning) any error
+// since a failed upload shouldn't stop the next gatherer run.
+func (u *InventoryUploader) SendDataToSSM(context context.T, inventoryItems []*ssm.InventoryItem) {
+	log := context.Log()
+
+	if len(inventoryItems) == 0 {
+		return
+	}
+
+	instanceID, err := platform.InstanceID()
+	if err != nil {
+		log.Errorf("Unable to fetch instance id to upload inventory data - %v", err.Error())
+		return
+	}
+
+	if _, err = u.ssm.PutInventory(&ssm.PutInventoryInput{
+		InstanceId: aws.String(instanceID),
+		Items:      inventoryItems,
+	}); err != nil {
+		log.Errorf("Unable to upload inventory data to SSM - %v", err.Error())
+	}
+}
+
+// UploadLargePayload gzips item's content, uploads it to the configured LargePayloadStore and
+// returns a reference small enough to pass to PutInventory in the item's place.
+func (u *InventoryUploader) UploadLargePayload(context context.T, item inventory.Item) (LargePayloadRef, error) {
+	if u.largePayloads == nil {
+		return LargePayloadRef{}, fmt.Errorf("no large payload store configured, cannot spill item %v", item.Name)
+	}
+
+	raw, err := jsonutil.Marshal(item.Content)
+	if err != nil {
+		return LargePayloadRef{}, err
+	}
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	if _, err = gz.Write([]byte(raw)); err != nil {
+		return LargePayloadRef{}, err
+	}
+	if err = gz.Close(); err != nil {
+		return LargePayloadRef{}, err
+	}
+
+	instanceID, err := platform.InstanceID()
+	if err != nil {
+		return LargePayloadRef{}, err
+	}
+
+	runID := times.ToIsoDashUTC(times.DefaultClock.Now())
+
+	ref, err := u.largePayloads.Put(context, instanceID, item.Name, runID, gzipped.Bytes())
+	if err != nil {
+		return LargePayloadRef{}, err
+	}
+
+	sum := sha256.Sum256([]byte(raw))
+	ref.Sha256 = hex.EncodeToString(sum[:])
+	ref.ItemCount = itemCount(item)
+	ref.Size = int64(len(raw))
+
+	return ref, nil
+}
+
+// itemCount returns the number of entries in item's content, for the "ItemCount" field of a
+// LargePayloadRef - most gatherers produce a list of records (packages, files, ...), but some
+// produce a single object.
+func itemCount(item inventory.Item) int {
+	if list, ok := item.Content.([]interface{}); ok {
+		return len(list)
+	}
+	return 1
+}
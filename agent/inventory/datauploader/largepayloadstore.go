@@ -0,0 +1,165 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datauploader
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// LargePayloadRef is a small pointer to an inventory item that was too large to send to SSM
+// directly and was spilled to S3 instead - this is what actually gets put on the PutInventory call
+// in place of the item itself.
+type LargePayloadRef struct {
+	Bucket    string
+	Key       string
+	Sha256    string
+	ItemCount int
+	Size      int64
+}
+
+// LargePayloadStore persists oversized inventory items out of band and reclaims them once they age
+// past a configured retention period.
+type LargePayloadStore interface {
+	// Put uploads the already-gzipped payload for gathererName's runID run and returns a reference
+	// to it.
+	Put(context context.T, instanceID, gathererName, runID string, payload []byte) (LargePayloadRef, error)
+
+	// ListOlderThan returns every stored payload whose age exceeds maxAge, for the reaper to clean up.
+	ListOlderThan(context context.T, maxAge time.Duration) ([]LargePayloadRef, error)
+
+	// Delete removes a previously stored payload.
+	Delete(context context.T, ref LargePayloadRef) error
+}
+
+// largePayloadKey builds the object key for a spilled inventory item: prefix/instanceID/gathererName/runID.json.gz
+func largePayloadKey(prefix, instanceID, gathererName, runID string) string {
+	return fmt.Sprintf("%v/%v/%v/%v.json.gz", prefix, instanceID, gathererName, runID)
+}
+
+// S3LargePayloadStore is the production LargePayloadStore, backed by a single S3 bucket/prefix.
+type S3LargePayloadStore struct {
+	client   *s3.S3
+	bucket   string
+	prefix   string
+	kmsKeyID string // optional - enables SSE-KMS when set, otherwise SSE-S3 is used
+}
+
+// NewS3LargePayloadStore creates an S3LargePayloadStore. kmsKeyID may be empty.
+func NewS3LargePayloadStore(client *s3.S3, bucket, prefix, kmsKeyID string) *S3LargePayloadStore {
+	return &S3LargePayloadStore{client: client, bucket: bucket, prefix: prefix, kmsKeyID: kmsKeyID}
+}
+
+// Put implements LargePayloadStore.
+func (s *S3LargePayloadStore) Put(context context.T, instanceID, gathererName, runID string, payload []byte) (LargePayloadRef, error) {
+	key := largePayloadKey(s.prefix, instanceID, gathererName, runID)
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(payload),
+	}
+	if s.kmsKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(s.kmsKeyID)
+	}
+
+	if _, err := s.client.PutObject(input); err != nil {
+		return LargePayloadRef{}, err
+	}
+
+	return LargePayloadRef{Bucket: s.bucket, Key: key}, nil
+}
+
+// ListOlderThan implements LargePayloadStore.
+func (s *S3LargePayloadStore) ListOlderThan(context context.T, maxAge time.Duration) ([]LargePayloadRef, error) {
+	var refs []LargePayloadRef
+	cutoff := time.Now().Add(-maxAge)
+
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			if obj.LastModified != nil && obj.LastModified.Before(cutoff) {
+				refs = append(refs, LargePayloadRef{Bucket: s.bucket, Key: aws.StringValue(obj.Key)})
+			}
+		}
+		return true
+	})
+
+	return refs, err
+}
+
+// Delete implements LargePayloadStore.
+func (s *S3LargePayloadStore) Delete(context context.T, ref LargePayloadRef) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(ref.Bucket), Key: aws.String(ref.Key)})
+	return err
+}
+
+// InMemoryLargePayloadStore is a LargePayloadStore fake for tests - it never touches S3.
+type InMemoryLargePayloadStore struct {
+	mu      sync.Mutex
+	objects map[string]inMemoryObject
+}
+
+type inMemoryObject struct {
+	payload  []byte
+	storedAt time.Time
+}
+
+// NewInMemoryLargePayloadStore creates an empty InMemoryLargePayloadStore.
+func NewInMemoryLargePayloadStore() *InMemoryLargePayloadStore {
+	return &InMemoryLargePayloadStore{objects: make(map[string]inMemoryObject)}
+}
+
+// Put implements LargePayloadStore.
+func (s *InMemoryLargePayloadStore) Put(context context.T, instanceID, gathererName, runID string, payload []byte) (LargePayloadRef, error) {
+	key := largePayloadKey("test", instanceID, gathererName, runID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = inMemoryObject{payload: payload, storedAt: time.Now()}
+
+	return LargePayloadRef{Bucket: "in-memory", Key: key}, nil
+}
+
+// ListOlderThan implements LargePayloadStore.
+func (s *InMemoryLargePayloadStore) ListOlderThan(context context.T, maxAge time.Duration) ([]LargePayloadRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	var refs []LargePayloadRef
+	for key, obj := range s.objects {
+		if obj.storedAt.Before(cutoff) {
+			refs = append(refs, LargePayloadRef{Bucket: "in-memory", Key: key})
+		}
+	}
+	return refs, nil
+}
+
+// Delete implements LargePayloadStore.
+func (s *InMemoryLargePayloadStore) Delete(context context.T, ref LargePayloadRef) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, ref.Key)
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datauploader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInMemoryLargePayloadStorePutThenListOlderThanRespectsAge(t *testing.T) {
+	store := NewInMemoryLargePayloadStore()
+
+	ref, err := store.Put(nil, "i-1234", "network", "run-1", []byte("payload"))
+	if err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	// Freshly stored, so a realistic retention window shouldn't consider it for reaping yet.
+	refs, err := store.ListOlderThan(nil, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("ListOlderThan returned error: %v", err)
+	}
+	if len(refs) != 0 {
+		t.Fatalf("expected no refs older than 24h right after Put, got %v", refs)
+	}
+
+	// A maxAge of zero means "older than now", which a just-stored object always satisfies.
+	refs, err = store.ListOlderThan(nil, 0)
+	if err != nil {
+		t.Fatalf("ListOlderThan returned error: %v", err)
+	}
+	if len(refs) != 1 || refs[0].Key != ref.Key {
+		t.Fatalf("expected the just-stored object to be listed with maxAge=0, got %v", refs)
+	}
+}
+
+func TestInMemoryLargePayloadStoreDeleteRemovesObject(t *testing.T) {
+	store := NewInMemoryLargePayloadStore()
+
+	ref, err := store.Put(nil, "i-1234", "network", "run-1", []byte("payload"))
+	if err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	if err := store.Delete(nil, ref); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	refs, err := store.ListOlderThan(nil, 0)
+	if err != nil {
+		t.Fatalf("ListOlderThan returned error: %v", err)
+	}
+	if len(refs) != 0 {
+		t.Fatalf("expected deleted object to no longer be listed, got %v", refs)
+	}
+}
+
+func TestLargePayloadKeyIsNamespacedByInstanceGathererAndRun(t *testing.T) {
+	key := largePayloadKey("inventory", "i-1234", "network", "run-1")
+	want := "inventory/i-1234/network/run-1.json.gz"
+	if key != want {
+		t.Errorf("largePayloadKey() = %v, want %v", key, want)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datauploader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+)
+
+// Reaper periodically deletes spilled large-payload objects older than retention, so a bucket
+// configured for inventory spillover doesn't grow without bound.
+type Reaper struct {
+	store     LargePayloadStore
+	retention time.Duration
+	interval  time.Duration
+	stop      chan struct{}
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+// NewReaper creates a Reaper that checks store for objects older than retention every interval.
+func NewReaper(store LargePayloadStore, retention, interval time.Duration) *Reaper {
+	return &Reaper{store: store, retention: retention, interval: interval, stop: make(chan struct{})}
+}
+
+// Start runs the reap loop in a background goroutine until Stop is called.
+func (r *Reaper) Start(context context.T) {
+	log := context.Log()
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				refs, err := r.store.ListOlderThan(context, r.retention)
+				if err != nil {
+					log.Errorf("Unable to list spilled inventory payloads for cleanup - %v", err.Error())
+					continue
+				}
+				for _, ref := range refs {
+					if err := r.store.Delete(context, ref); err != nil {
+						log.Errorf("Unable to delete spilled inventory payload s3://%v/%v - %v", ref.Bucket, ref.Key, err.Error())
+					}
+				}
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the reap loop started by Start. Safe to call more than once - a repeated call is a
+// no-op instead of panicking on a double close of r.stop.
+func (r *Reaper) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped {
+		return
+	}
+	r.stopped = true
+	close(r.stop)
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package events
+
+import "sync"
+
+// subscriberChanBufferSize bounds how many unconsumed events a single subscriber may queue before
+// the bus starts dropping events for it. A slow subscriber must never be able to block gatherer
+// execution.
+const subscriberChanBufferSize = 32
+
+// Publisher publishes inventory lifecycle events to any interested Subscribers.
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Subscriber registers interest in inventory lifecycle events.
+type Subscriber interface {
+	// Subscribe returns a channel of events matching filter (or every event, if filter is nil) and
+	// a cancel func that unregisters the subscription and closes the channel.
+	Subscribe(filter EventFilter) (<-chan Event, func())
+}
+
+// Bus is a Publisher/Subscriber pair backed by buffered per-subscriber channels. A subscriber that
+// falls behind has events dropped for it rather than blocking Publish.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[int]*subscription
+	next int
+}
+
+type subscription struct {
+	filter EventFilter
+	ch     chan Event
+}
+
+// NewBus creates an empty event Bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]*subscription)}
+}
+
+// Publish implements Publisher. It never blocks: a subscriber whose channel is full simply misses
+// the event.
+func (b *Bus) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// slow consumer - drop the event rather than block the publisher
+		}
+	}
+}
+
+// Subscribe implements Subscriber.
+func (b *Bus) Subscribe(filter EventFilter) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+
+	sub := &subscription{filter: filter, ch: make(chan Event, subscriberChanBufferSize)}
+	b.subs[id] = sub
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[id]; ok {
+			delete(b.subs, id)
+			close(sub.ch)
+		}
+	}
+
+	return sub.ch, cancel
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package events
+
+import "testing"
+
+func TestBusPublishDeliversToMatchingSubscriberOnly(t *testing.T) {
+	bus := NewBus()
+
+	policyCh, cancelPolicy := bus.Subscribe(ForTypes(TypePolicyLoaded))
+	defer cancelPolicy()
+	uploadCh, cancelUpload := bus.Subscribe(ForTypes(TypeUploadStarted))
+	defer cancelUpload()
+
+	bus.Publish(PolicyLoaded{GathererCount: 3})
+
+	select {
+	case e := <-policyCh:
+		if loaded, ok := e.(PolicyLoaded); !ok || loaded.GathererCount != 3 {
+			t.Fatalf("unexpected event delivered to matching subscriber: %#v", e)
+		}
+	default:
+		t.Fatal("expected matching subscriber to receive the event")
+	}
+
+	select {
+	case e := <-uploadCh:
+		t.Fatalf("non-matching subscriber should not have received an event, got %#v", e)
+	default:
+	}
+}
+
+func TestBusSubscribeWithNilFilterMatchesEverything(t *testing.T) {
+	bus := NewBus()
+
+	ch, cancel := bus.Subscribe(nil)
+	defer cancel()
+
+	bus.Publish(GathererStarted{Name: "network"})
+	bus.Publish(UploadStarted{ItemCount: 1})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ch:
+		default:
+			t.Fatalf("expected nil-filter subscriber to receive event %d", i)
+		}
+	}
+}
+
+func TestBusPublishDropsEventForFullSubscriberRatherThanBlocking(t *testing.T) {
+	bus := NewBus()
+
+	ch, cancel := bus.Subscribe(nil)
+	defer cancel()
+
+	// Fill the subscriber's buffer without reading from it, then publish one more - Publish must
+	// not block, and the overflow event must simply be dropped for this subscriber.
+	for i := 0; i < subscriberChanBufferSize; i++ {
+		bus.Publish(GathererStarted{Name: "network"})
+	}
+	bus.Publish(GathererStarted{Name: "dropped"})
+
+	if len(ch) != subscriberChanBufferSize {
+		t.Fatalf("expected channel to stay at its buffer size %v, got %v", subscriberChanBufferSize, len(ch))
+	}
+}
+
+func TestBusCancelClosesChannelAndStopsDelivery(t *testing.T) {
+	bus := NewBus()
+
+	ch, cancel := bus.Subscribe(nil)
+	cancel()
+
+	if _, ok := <-ch; ok {
+		t.Fatal("expected channel to be closed after cancel")
+	}
+
+	// Publishing after cancel must not panic (e.g. by sending on the now-closed channel) and must
+	// not resurrect the subscription.
+	bus.Publish(GathererStarted{Name: "network"})
+}
+
+func TestBusCancelIsIdempotent(t *testing.T) {
+	bus := NewBus()
+
+	_, cancel := bus.Subscribe(nil)
+	cancel()
+	cancel()
+}
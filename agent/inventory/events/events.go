@@ -0,0 +1,131 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package events exposes a typed publish/subscribe bus for inventory plugin lifecycle events, so
+// other components (association, health, a future long-running plugin controller) can react to
+// inventory state changes instead of scraping log lines.
+package events
+
+import "time"
+
+// Type identifies the concrete kind of an Event.
+type Type string
+
+const (
+	// TypePolicyLoaded fires once an inventory policy document has been read and parsed.
+	TypePolicyLoaded Type = "PolicyLoaded"
+	// TypeGathererStarted fires immediately before a gatherer is invoked.
+	TypeGathererStarted Type = "GathererStarted"
+	// TypeGathererCompleted fires once a gatherer has returned, successfully or not.
+	TypeGathererCompleted Type = "GathererCompleted"
+	// TypeSizeLimitExceeded fires when collected inventory data breaches a configured size limit.
+	TypeSizeLimitExceeded Type = "SizeLimitExceeded"
+	// TypeUploadStarted fires immediately before inventory data is sent to SSM.
+	TypeUploadStarted Type = "UploadStarted"
+	// TypeUploadCompleted fires once the upload to SSM has returned, successfully or not.
+	TypeUploadCompleted Type = "UploadCompleted"
+	// TypeScheduleSnapshot fires after every scheduler tick with the current per-gatherer schedule.
+	TypeScheduleSnapshot Type = "ScheduleSnapshot"
+)
+
+// Event is implemented by every event published on the bus. Type identifies which concrete struct
+// it is, so a Subscriber can type-switch without reflection.
+type Event interface {
+	EventType() Type
+}
+
+// PolicyLoaded is published after ApplyInventoryPolicy successfully parses a policy document.
+type PolicyLoaded struct {
+	GathererCount int
+}
+
+// EventType implements Event.
+func (PolicyLoaded) EventType() Type { return TypePolicyLoaded }
+
+// GathererStarted is published immediately before VerifyAndRunGatherers invokes a gatherer.
+type GathererStarted struct {
+	Name string
+}
+
+// EventType implements Event.
+func (GathererStarted) EventType() Type { return TypeGathererStarted }
+
+// GathererCompleted is published once a gatherer returns.
+type GathererCompleted struct {
+	Name     string
+	Item     interface{}
+	Duration time.Duration
+	Err      error
+}
+
+// EventType implements Event.
+func (GathererCompleted) EventType() Type { return TypeGathererCompleted }
+
+// SizeLimitExceeded is published when collected inventory data breaches a configured size limit.
+type SizeLimitExceeded struct {
+	Name string
+}
+
+// EventType implements Event.
+func (SizeLimitExceeded) EventType() Type { return TypeSizeLimitExceeded }
+
+// UploadStarted is published immediately before inventory data is sent to SSM.
+type UploadStarted struct {
+	ItemCount int
+}
+
+// EventType implements Event.
+func (UploadStarted) EventType() Type { return TypeUploadStarted }
+
+// UploadCompleted is published once the upload to SSM has returned.
+type UploadCompleted struct {
+	ItemCount int
+	Err       error
+}
+
+// EventType implements Event.
+func (UploadCompleted) EventType() Type { return TypeUploadCompleted }
+
+// GathererSchedule is one gatherer's scheduling state, as reported in a ScheduleSnapshot.
+type GathererSchedule struct {
+	Name              string
+	NextRun           time.Time
+	ConsecutiveErrors int
+	Healthy           bool
+}
+
+// ScheduleSnapshot is published after every scheduler tick so other components (association,
+// health, future observability tooling) can see per-gatherer scheduling state without reaching
+// into the inventory Plugin's internals.
+type ScheduleSnapshot struct {
+	Gatherers []GathererSchedule
+}
+
+// EventType implements Event.
+func (ScheduleSnapshot) EventType() Type { return TypeScheduleSnapshot }
+
+// EventFilter decides whether a Subscriber wants to receive a given event. A nil EventFilter
+// matches every event.
+type EventFilter func(Event) bool
+
+// ForTypes returns an EventFilter that matches any of the given event types.
+func ForTypes(types ...Type) EventFilter {
+	return func(e Event) bool {
+		for _, t := range types {
+			if e.EventType() == t {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package gatherers contains the registry of inventory gatherers known to the agent - both the
+// gatherers compiled into the binary and any custom gatherers discovered on disk.
+package gatherers
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+)
+
+// T represents an inventory gatherer capable of collecting a single inventory.Item.
+type T interface {
+	Run(context context.T, configuration inventory.Config) (inventory.Item, error)
+	Name() string
+}
+
+// Registry is a lookup of gatherer name -> gatherer implementation. It is safe for concurrent use:
+// Rescan is called from the policy-watch goroutine while Execute's scheduler goroutines read it
+// concurrently to run due gatherers.
+type Registry struct {
+	mu    sync.RWMutex
+	items map[string]T
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{items: make(map[string]T)}
+}
+
+// Get returns the gatherer registered under name, if any.
+func (r *Registry) Get(name string) (T, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	gatherer, ok := r.items[name]
+	return gatherer, ok
+}
+
+// Register adds gatherer to the registry under name, overwriting any gatherer already registered
+// under it. Exported (unlike set) so tests in other packages - notably inventory's own - can
+// populate a registry with a fake gatherer without needing a real .so file on disk.
+func (r *Registry) Register(name string, gatherer T) {
+	r.set(name, gatherer)
+}
+
+// set registers gatherer under name, overwriting any gatherer already registered under it.
+func (r *Registry) set(name string, gatherer T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[name] = gatherer
+}
+
+// LoadGatherers returns the registry of gatherers compiled into the binary merged with any custom
+// gatherers found in appCfg.Ssm.CustomInventoryDir. Call Rescan afterwards to pick up custom
+// gatherers that are added, changed or removed after startup.
+func LoadGatherers(context context.T) *Registry {
+	registry := NewRegistry()
+
+	// for now only a dummy application gatherer is loaded in memory
+	registerBuiltInGatherers(registry)
+
+	getPluginLoader(context).rescan(registry)
+
+	return registry
+}
+
+// Rescan re-scans the custom inventory gatherer directory and merges newly discovered, changed or
+// removed gatherers into registry. Built-in gatherers are left untouched. It is cheap to call on
+// every policy refresh - unchanged plugins are served from an internal cache keyed by file mtime.
+func Rescan(context context.T, registry *Registry) {
+	getPluginLoader(context).rescan(registry)
+}
+
+// registerBuiltInGatherers adds the gatherers compiled into this binary to registry.
+func registerBuiltInGatherers(registry *Registry) {
+	// NOTE: built-in gatherers register themselves here, e.g.:
+	// app := application.Gatherer(context)
+	// registry.set(app.Name(), app)
+}
@@ -0,0 +1,177 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gatherers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+)
+
+// customGathererSymbol is the exported symbol every custom gatherer .so must provide.
+const customGathererSymbol = "NewGatherer"
+
+// customGathererPattern is the filename glob a custom gatherer .so must match to be picked up.
+const customGathererPattern = "*_gatherer.so"
+
+// NewGathererFunc is the signature custom gatherer plugins must expose via the NewGatherer
+// exported symbol.
+type NewGathererFunc func(context context.T) (T, string, error)
+
+// pluginLoader scans a directory for custom gatherer plugins (.so files) and merges them into a
+// Registry. It caches the gatherer each file produced, keyed by file path, so repeated rescans only
+// reopen a .so when its mtime has changed.
+type pluginLoader struct {
+	context context.T
+	dir     string
+
+	mu     sync.Mutex
+	loaded map[string]*loadedPlugin // keyed by file path, as returned by filepath.Glob
+
+	// openFunc loads a single candidate file, returning its gatherer and declared name. It
+	// defaults to l.open; tests override it so the duplicate-name and mtime-invalidation logic in
+	// rescan can be exercised without building real *.so plugins.
+	openFunc func(file string) (T, string, error)
+}
+
+type loadedPlugin struct {
+	name     string
+	mtime    time.Time
+	gatherer T
+}
+
+var (
+	loadersMu sync.Mutex
+	loaders   = map[string]*pluginLoader{}
+)
+
+// getPluginLoader returns the pluginLoader for the configured custom inventory directory, creating
+// and caching one on first use so plugin symbol resolution is shared across rescans.
+func getPluginLoader(context context.T) *pluginLoader {
+	dir := context.AppConfig().Ssm.CustomInventoryDir
+
+	loadersMu.Lock()
+	defer loadersMu.Unlock()
+
+	if loader, ok := loaders[dir]; ok {
+		return loader
+	}
+
+	loader := &pluginLoader{
+		context: context,
+		dir:     dir,
+		loaded:  make(map[string]*loadedPlugin),
+	}
+	loader.openFunc = loader.open
+	loaders[dir] = loader
+	return loader
+}
+
+// rescan walks the plugin directory and merges any newly discovered or changed gatherers into
+// registry. It is safe to call repeatedly - a .so that fails to load only skips that one gatherer
+// rather than the entire directory, and a name collision is logged and the later file skipped.
+func (l *pluginLoader) rescan(registry *Registry) {
+	log := l.context.Log()
+
+	if l.dir == "" {
+		return
+	}
+
+	matches, err := filepath.Glob(filepath.Join(l.dir, customGathererPattern))
+	if err != nil {
+		log.Errorf("Unable to scan custom inventory gatherer directory %v - %v", l.dir, err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seenByName := map[string]string{} // gatherer name -> source file, to catch duplicates within this scan
+	present := make(map[string]bool, len(matches))
+
+	for _, file := range matches {
+		present[file] = true
+		info, err := os.Stat(file)
+		if err != nil {
+			log.Errorf("Unable to stat custom inventory gatherer %v - %v", file, err)
+			continue
+		}
+
+		cached, isCached := l.loaded[file]
+		if !isCached || !cached.mtime.Equal(info.ModTime()) {
+			gatherer, name, err := l.openFunc(file)
+			if err != nil {
+				log.Errorf("Failed to load custom inventory gatherer %v - %v", file, err)
+				continue
+			}
+			cached = &loadedPlugin{name: name, mtime: info.ModTime(), gatherer: gatherer}
+			l.loaded[file] = cached
+			log.Infof("Loaded custom inventory gatherer %v from %v", name, file)
+		}
+
+		if existing, isDuplicate := seenByName[cached.name]; isDuplicate {
+			log.Errorf("Duplicate custom inventory gatherer name %v from %v, already registered from %v - skipping",
+				cached.name, file, existing)
+			continue
+		}
+
+		seenByName[cached.name] = file
+		registry.set(cached.name, cached.gatherer)
+	}
+
+	// unregister gatherers whose .so file is gone, unless another file in this scan provides the name
+	for file, cached := range l.loaded {
+		if present[file] {
+			continue
+		}
+		delete(l.loaded, file)
+		if _, stillProvided := seenByName[cached.name]; !stillProvided {
+			registry.mu.Lock()
+			delete(registry.items, cached.name)
+			registry.mu.Unlock()
+		}
+	}
+}
+
+// open loads a single custom gatherer .so and resolves its NewGatherer symbol.
+func (l *pluginLoader) open(file string) (gatherer T, name string, err error) {
+	p, err := plugin.Open(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not open plugin: %v", err)
+	}
+
+	sym, err := p.Lookup(customGathererSymbol)
+	if err != nil {
+		return nil, "", fmt.Errorf("plugin does not export %v: %v", customGathererSymbol, err)
+	}
+
+	newGatherer, ok := sym.(func(context.T) (T, string, error))
+	if !ok {
+		return nil, "", fmt.Errorf("%v has unexpected type %T", customGathererSymbol, sym)
+	}
+
+	if gatherer, name, err = newGatherer(l.context); err != nil {
+		return nil, "", fmt.Errorf("NewGatherer returned an error: %v", err)
+	}
+	if name == "" {
+		return nil, "", fmt.Errorf("NewGatherer returned an empty gatherer name")
+	}
+
+	return gatherer, name, nil
+}
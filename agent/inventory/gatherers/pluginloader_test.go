@@ -0,0 +1,191 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gatherers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+)
+
+// fakeGatherer is a minimal T used to stand in for a gatherer loaded from a .so, without actually
+// building one.
+type fakeGatherer struct{ name string }
+
+func (f *fakeGatherer) Run(context context.T, configuration inventory.Config) (inventory.Item, error) {
+	return inventory.Item{Name: f.name}, nil
+}
+
+func (f *fakeGatherer) Name() string { return f.name }
+
+// newTestLoader creates a pluginLoader for dir with openFunc stubbed out, so rescan's caching and
+// duplicate-name logic can be exercised without resolving a real plugin.Open symbol.
+func newTestLoader(dir string, open func(file string) (T, string, error)) *pluginLoader {
+	return &pluginLoader{
+		context:  context.NewMockDefault(),
+		dir:      dir,
+		loaded:   make(map[string]*loadedPlugin),
+		openFunc: open,
+	}
+}
+
+func touchPlugin(t *testing.T, dir, name string) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, []byte("not a real plugin"), 0644); err != nil {
+		t.Fatalf("could not write fake plugin file %v: %v", file, err)
+	}
+	return file
+}
+
+func TestPluginLoaderRescanRegistersEachDiscoveredGatherer(t *testing.T) {
+	dir := t.TempDir()
+	touchPlugin(t, dir, "network_gatherer.so")
+	touchPlugin(t, dir, "disk_gatherer.so")
+
+	opens := 0
+	loader := newTestLoader(dir, func(file string) (T, string, error) {
+		opens++
+		name := filepath.Base(file)
+		return &fakeGatherer{name: name}, name, nil
+	})
+
+	registry := NewRegistry()
+	loader.rescan(registry)
+
+	if opens != 2 {
+		t.Fatalf("expected both candidate files to be opened, got %v opens", opens)
+	}
+	if _, ok := registry.Get("network_gatherer.so"); !ok {
+		t.Error("expected network_gatherer.so to be registered")
+	}
+	if _, ok := registry.Get("disk_gatherer.so"); !ok {
+		t.Error("expected disk_gatherer.so to be registered")
+	}
+}
+
+func TestPluginLoaderRescanDoesNotReopenUnchangedFile(t *testing.T) {
+	dir := t.TempDir()
+	touchPlugin(t, dir, "network_gatherer.so")
+
+	opens := 0
+	loader := newTestLoader(dir, func(file string) (T, string, error) {
+		opens++
+		return &fakeGatherer{name: "network"}, "network", nil
+	})
+
+	registry := NewRegistry()
+	loader.rescan(registry)
+	loader.rescan(registry)
+	loader.rescan(registry)
+
+	if opens != 1 {
+		t.Errorf("expected an unchanged file to be opened only once across rescans, got %v opens", opens)
+	}
+}
+
+func TestPluginLoaderRescanReopensWhenMtimeChanges(t *testing.T) {
+	dir := t.TempDir()
+	file := touchPlugin(t, dir, "network_gatherer.so")
+
+	opens := 0
+	loader := newTestLoader(dir, func(file string) (T, string, error) {
+		opens++
+		return &fakeGatherer{name: "network"}, "network", nil
+	})
+
+	registry := NewRegistry()
+	loader.rescan(registry)
+
+	// advance the mtime so rescan sees the file as changed, the way an operator replacing the .so
+	// on disk would.
+	newMtime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, newMtime, newMtime); err != nil {
+		t.Fatalf("could not change mtime of %v: %v", file, err)
+	}
+
+	loader.rescan(registry)
+
+	if opens != 2 {
+		t.Errorf("expected a changed mtime to trigger a reopen, got %v opens", opens)
+	}
+}
+
+func TestPluginLoaderRescanSkipsDuplicateGathererNameWithinSameScan(t *testing.T) {
+	dir := t.TempDir()
+	touchPlugin(t, dir, "a_gatherer.so")
+	touchPlugin(t, dir, "b_gatherer.so")
+
+	loader := newTestLoader(dir, func(file string) (T, string, error) {
+		// both files declare the same gatherer name - whichever sorts first in the glob should
+		// win, and the other should be skipped rather than overwriting it.
+		return &fakeGatherer{name: filepath.Base(file)}, "duplicate", nil
+	})
+
+	registry := NewRegistry()
+	loader.rescan(registry)
+
+	gatherer, ok := registry.Get("duplicate")
+	if !ok {
+		t.Fatal("expected the first-seen gatherer to be registered")
+	}
+	if gatherer.(*fakeGatherer).name != "a_gatherer.so" {
+		t.Errorf("expected the gatherer from a_gatherer.so (first in glob order) to win, got %v", gatherer)
+	}
+	if len(loader.loaded) != 2 {
+		t.Errorf("expected both files to still be individually cached, got %v", len(loader.loaded))
+	}
+}
+
+func TestPluginLoaderRescanSkipsFileThatFailsToOpenButLoadsOthers(t *testing.T) {
+	dir := t.TempDir()
+	touchPlugin(t, dir, "broken_gatherer.so")
+	touchPlugin(t, dir, "network_gatherer.so")
+
+	loader := newTestLoader(dir, func(file string) (T, string, error) {
+		if filepath.Base(file) == "broken_gatherer.so" {
+			return nil, "", fmt.Errorf("simulated plugin.Open failure")
+		}
+		return &fakeGatherer{name: "network"}, "network", nil
+	})
+
+	registry := NewRegistry()
+	loader.rescan(registry)
+
+	if _, ok := registry.Get("broken_gatherer.so"); ok {
+		t.Error("a gatherer that failed to open should never be registered")
+	}
+	if _, ok := registry.Get("network"); !ok {
+		t.Error("expected the other, healthy gatherer to still be registered")
+	}
+}
+
+func TestPluginLoaderRescanWithNoDirectoryConfiguredIsNoOp(t *testing.T) {
+	loader := newTestLoader("", func(file string) (T, string, error) {
+		t.Fatal("openFunc should never be called when no directory is configured")
+		return nil, "", nil
+	})
+
+	registry := NewRegistry()
+	loader.rescan(registry)
+
+	if len(registry.items) != 0 {
+		t.Errorf("expected an empty registry, got %v", registry.items)
+	}
+}
@@ -17,19 +17,22 @@ package inventory
 import (
 	"encoding/json"
 	"fmt"
-	"path"
+	"sync"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
-	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/inventory/datauploader"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/events"
 	"github.com/aws/amazon-ssm-agent/agent/inventory/gatherers"
 	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/policysource"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/message/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/ssm"
-	"github.com/carlescere/scheduler"
 )
 
 //TODO: integration with on-demand plugin - so that associate plugin can invoke this plugin
@@ -37,31 +40,57 @@ import (
 
 // Plugin encapsulates the logic of configuring, starting and stopping inventory plugin
 type Plugin struct {
-	//NOTE: Unless we integrate inventory plugin with associate/mds plugin, the only way to ingest inventory policy
-	//document would be through files - where this plugin will periodically monitor for any changes to policy doc.
 	context    context.T
 	stopPolicy *sdkutil.StopPolicy
 	ssm        *ssm.SSM
-	//job is a scheduled job, which looks for updated inventory policy at a given location (this will be removed
-	//when Plugin will be integrated with associate plugin)
-	job                *scheduler.Job
+
 	frequencyInMinutes int
-	//location stores inventory policy doc
-	location string
 	//isEnabled enables inventory plugin, if this is false - then inventory plugin will not run.
 	isEnabled bool
-	//registeredGatherers is a map of all supported inventory gatherers.
-	registeredGatherers gatherers.Registry
+	//registeredGatherers is the registry of all supported inventory gatherers, safe for the
+	//concurrent reads and rescan-driven writes Execute's goroutines perform on it.
+	registeredGatherers *gatherers.Registry
 
 	//uploader handles uploading inventory data to SSM.
 	uploader datauploader.T
+	//reaper cleans up large-payload objects that have spilled to S3 past their retention period.
+	//Nil unless a large-payload bucket is configured.
+	reaper *datauploader.Reaper
+
+	//publisher emits inventory lifecycle events (policy loaded, gatherer run, upload done, ...) so
+	//other plugins can react instead of polling files or scraping logs. May be nil.
+	publisher events.Publisher
+
+	//policySource supplies the inventory policy document, from whichever of file/S3/association is
+	//configured and non-empty.
+	policySource policysource.T
+	//associationSource is also reachable directly so the association plugin can push documents into
+	//it as it parses them - see UpdatePolicyFromAssociation.
+	associationSource *policysource.AssociationPolicySource
+
+	//stopChan signals the Watch consumer goroutine and the scheduler tick goroutine started by
+	//Execute to exit.
+	stopChan chan struct{}
+	//stopMu guards stopped so a repeated RequestStop call is a no-op instead of panicking on a
+	//double close of stopChan.
+	stopMu  sync.Mutex
+	stopped bool
+
+	//schedule tracks per-gatherer run history (next run time, backoff, circuit breaker) so Execute
+	//can run each gatherer on its own interval instead of the whole policy on one shared schedule.
+	schedule *schedule
+	//defaultInterval is how often a gatherer runs when its Config doesn't set IntervalMinutes.
+	defaultInterval time.Duration
+	//maxConcurrency bounds how many gatherers Execute will run at once.
+	maxConcurrency int
 }
 
-// NewPlugin creates a new inventory core plugin.
-func NewPlugin(context context.T) (*Plugin, error) {
+// NewPlugin creates a new inventory core plugin. publisher is optional - pass nil if nothing needs
+// to observe inventory lifecycle events yet.
+func NewPlugin(context context.T, publisher events.Publisher) (*Plugin, error) {
 	var appCfg appconfig.SsmagentConfig
 	var err error
-	var p = Plugin{}
+	var p = Plugin{publisher: publisher}
 
 	c := context.With("[" + inventory.InventoryPluginName + "]")
 	log := c.Log()
@@ -84,78 +113,142 @@ func NewPlugin(context context.T) (*Plugin, error) {
 	p.stopPolicy = sdkutil.NewStopPolicy(inventory.InventoryPluginName, inventory.ErrorThreshold)
 	p.ssm = ssm.New(session.New(cfg))
 
-	//location - path where inventory policy doc is stored. (Note: this is temporary till we integrate with
-	//associate plugin)
-	p.location = appconfig.DefaultProgramFolder
-
 	//for now we are using the same frequency as that of health plugin to look & apply new inventory policy
 	p.frequencyInMinutes = appCfg.Ssm.HealthFrequencyMinutes
 
 	//loads all registered gatherers (for now only a dummy application gatherer is loaded in memory)
 	p.registeredGatherers = gatherers.LoadGatherers(context)
 
+	//schedule turns the single policy-wide frequency above into a default - a gatherer with its own
+	//Config.IntervalMinutes runs on its own cadence, and a slow or failing gatherer no longer blocks
+	//the rest from running.
+	p.schedule = newSchedule()
+	p.defaultInterval = time.Duration(p.frequencyInMinutes) * time.Minute
+	p.maxConcurrency = appCfg.Ssm.InventoryMaxConcurrency
+	if p.maxConcurrency <= 0 {
+		p.maxConcurrency = defaultMaxConcurrency
+	}
+
+	//if a spill bucket is configured, oversized items are shipped there instead of being dropped;
+	//a reaper cleans up objects past the configured retention.
+	var largePayloads datauploader.LargePayloadStore
+	if appCfg.Ssm.InventoryLargePayloadBucket != "" {
+		store := datauploader.NewS3LargePayloadStore(
+			s3.New(session.New(cfg)),
+			appCfg.Ssm.InventoryLargePayloadBucket,
+			appCfg.Ssm.InventoryLargePayloadPrefix,
+			appCfg.Ssm.InventoryLargePayloadKmsKeyId)
+		largePayloads = store
+
+		retentionDays := appCfg.Ssm.InventoryLargePayloadRetentionDays
+		if retentionDays <= 0 {
+			retentionDays = defaultLargePayloadRetentionDays
+		}
+		retention := time.Duration(retentionDays) * 24 * time.Hour
+		p.reaper = datauploader.NewReaper(store, retention, time.Hour)
+		p.reaper.Start(c)
+	}
+
 	//initializes SSM Inventory uploader
-	if p.uploader, err = datauploader.NewInventoryUploader(context); err != nil {
+	if p.uploader, err = datauploader.NewInventoryUploader(context, largePayloads); err != nil {
 		err = log.Errorf("Unable to configure SSM Inventory uploader - %v", err.Error())
 	}
 
+	//compose the policy sources, in priority order. The association document goes first - driving
+	//inventory from an association instead of a sidecar file is the whole point of supporting it, so
+	//it must not be shadowed by a pre-existing inventory.json. S3 is next when configured, and the
+	//local file is the last-resort fallback that keeps upgrades from the old behaviour working. The
+	//first of them with a non-empty policy wins.
+	pollInterval := time.Duration(p.frequencyInMinutes) * time.Minute
+
+	p.associationSource = policysource.NewAssociationPolicySource(inventory.InventoryPluginName)
+	sources := []policysource.T{p.associationSource}
+
+	if appCfg.Ssm.InventoryPolicyBucket != "" {
+		sources = append(sources, policysource.NewS3PolicySource(s3.New(session.New(cfg)), appCfg.Ssm.InventoryPolicyBucket, appCfg.Ssm.InventoryPolicyKey))
+	}
+
+	sources = append(sources, policysource.NewFilePolicySource(appconfig.DefaultProgramFolder, inventory.InventoryPolicyDocName, pollInterval))
+
+	p.policySource = policysource.NewCompositeSource(sources...)
+
 	return &p, err
 }
 
-// ApplyInventoryPolicy applies basic instance information inventory data in SSM
+// UpdatePolicyFromAssociation lets the association plugin drive the inventory policy directly from
+// a parsed association document, instead of inventory having to wait on a sidecar policy file.
+func (p *Plugin) UpdatePolicyFromAssociation(payload *messageContracts.SendCommandPayload) {
+	p.associationSource.Update(payload)
+}
+
+// publish emits event on p.publisher, if one was configured.
+func (p *Plugin) publish(event events.Event) {
+	if p.publisher != nil {
+		p.publisher.Publish(event)
+	}
+}
+
+// ApplyInventoryPolicy fetches the current inventory policy from p.policySource and applies it.
+// Execute no longer polls on a fixed schedule - this is kept for callers that want to force an
+// immediate application (e.g. the first run before Watch delivers anything).
 func (p *Plugin) ApplyInventoryPolicy() {
-	//NOTE: this will only be used until we integrate with associate plugin
 	log := p.context.Log()
-	log.Infof("Looking for SSM Inventory policy in %v", p.location)
 
-	doc := path.Join(p.location, inventory.InventoryPolicyDocName)
-	//get latest instanceInfo inventory item
-	if fileutil.Exists(doc) {
-		log.Infof("Applying Inventory policy")
+	policy, _, err := p.policySource.Fetch(p.context)
+	if err == policysource.ErrNoPolicy {
+		log.Infof("No inventory policy to apply")
+		return
+	}
+	if err != nil {
+		log.Infof("Encountered error while fetching Inventory policy. Error - %v", err.Error())
+		return
+	}
 
-		var policy inventory.Policy
-		var inventoryItems []*ssm.InventoryItem
+	p.applyPolicy(policy)
+}
 
-		//read file
-		if content, err := fileutil.ReadAllText(doc); err == nil {
+// applyPolicy runs every gatherer named in policy and uploads the collected data to SSM.
+func (p *Plugin) applyPolicy(policy inventory.Policy) {
+	log := p.context.Log()
+	log.Infof("Applying Inventory policy")
 
-			if err = json.Unmarshal([]byte(content), &policy); err != nil {
-				log.Infof("Encountered error while reading Inventory policy at %v. Error - %v",
-					doc,
-					err.Error())
-				log.Infof("Skipping execution of inventory policy doc.")
-				return
-			}
+	//pick up any custom gatherer .so files that were added, changed or removed since the last run
+	gatherers.Rescan(p.context, p.registeredGatherers)
 
-			if items, err := p.VerifyAndRunGatherers(policy); err != nil {
-				log.Infof("Encountered error while executing inventory policy: %v", err.Error())
-				return
-			} else {
-				//log collected data before sending
-				d, _ := json.Marshal(items)
-				log.Infof("Collected Inventory data: %v", string(d))
+	p.publish(events.PolicyLoaded{GathererCount: len(policy.InventoryPolicy)})
 
-				if inventoryItems, err = p.uploader.ConvertToSsmInventoryItems(p.context, items); err != nil {
-					log.Infof("Encountered error in converting data to SSM InventoryItems - %v. Skipping upload to SSM", err.Error())
-				}
+	items, err := p.VerifyAndRunGatherers(policy)
+	if err != nil {
+		log.Infof("Encountered error while executing inventory policy: %v", err.Error())
+		return
+	}
 
-				p.uploader.SendDataToSSM(p.context, inventoryItems)
-			}
+	p.uploadItems(items)
+}
 
-		} else {
-			log.Infof("Unable to read inventory policy from : %v because of error - %v", doc, err.Error())
-			return
-		}
-	} else {
-		log.Infof("No inventory policy to apply")
+// uploadItems converts items to SSM's wire format and uploads them. It is shared by the manual
+// ApplyInventoryPolicy path and the scheduler tick loop started by Execute.
+func (p *Plugin) uploadItems(items []inventory.Item) {
+	log := p.context.Log()
+
+	//log collected data before sending
+	d, _ := json.Marshal(items)
+	log.Infof("Collected Inventory data: %v", string(d))
+
+	inventoryItems, err := p.uploader.ConvertToSsmInventoryItems(p.context, items)
+	if err != nil {
+		log.Infof("Encountered error in converting data to SSM InventoryItems - %v. Skipping upload to SSM", err.Error())
 	}
 
-	return
+	p.publish(events.UploadStarted{ItemCount: len(inventoryItems)})
+	p.uploader.SendDataToSSM(p.context, inventoryItems)
+	p.publish(events.UploadCompleted{ItemCount: len(inventoryItems)})
 }
 
 // VerifyAndRunGatherers verifies if gatherers is registered and then invokes it to return the result (containing
-// inventory data). It returns error if gatherer is not registered or if at any stage the data returned breaches size
-// limit
+// inventory data). It returns error if a gatherer is not registered, if a gatherer itself errors, or if the
+// aggregate size of the collected items breaches the total size limit - an item that individually breaches
+// the per-type limit is not an error, it is spilled to S3 and replaced with a LargePayloadRef instead.
 func (p *Plugin) VerifyAndRunGatherers(policy inventory.Policy) (items []inventory.Item, err error) {
 	log := p.context.Log()
 	log.Infof("Verifying if gatherers are registered and then running them")
@@ -165,27 +258,45 @@ func (p *Plugin) VerifyAndRunGatherers(policy inventory.Policy) (items []invento
 	//registered gatherers - this is because we don't send partial inventory data as part of 1 inventory policy.
 	//Either we send full set of inventory data as defined in policy - or we send nothing.
 
-	//2) Currently all gatherers will be invoked in synchronous & sequential fashion.
-	//Parallel execution of gatherers hinges upon inventory plugin becoming a long running plugin - which will be
-	//mainly for custom inventory gatherer to send data independently of associate.
+	//2) This method still runs gatherers synchronously & sequentially - it only exists for callers
+	//that want to force an immediate, one-shot application of policy (see ApplyInventoryPolicy).
+	//The long-running plugin's own scheduler loop (runScheduleLoop/runDueGatherers) is what runs
+	//gatherers in parallel, bounded by maxConcurrency, on their own per-gatherer interval.
 
 	for name, _ := range policy.InventoryPolicy {
 		//find out if the gatherer is indeed registered.
-		if gatherer, isGathererRegistered := p.registeredGatherers[name]; !isGathererRegistered {
+		if gatherer, isGathererRegistered := p.registeredGatherers.Get(name); !isGathererRegistered {
 			err = log.Errorf("Unrecognized inventory gatherer - %v ", name)
 			break
 		} else {
 			var item inventory.Item
 			log.Infof("Invoking gatherer - %v", name)
 
-			if item, err = gatherer.Run(p.context, policy.InventoryPolicy[name]); err != nil {
+			p.publish(events.GathererStarted{Name: name})
+			start := time.Now()
+			item, err = gatherer.Run(p.context, policy.InventoryPolicy[name])
+			p.publish(events.GathererCompleted{Name: name, Item: item, Duration: time.Since(start), Err: err})
+
+			if err != nil {
 				err = log.Errorf("Encountered error while executing %v. Error - %v", name, err.Error())
 				break
 			} else {
+				if itemExceedsPerTypeLimit(item) {
+					p.publish(events.SizeLimitExceeded{Name: name})
+					log.Infof("Inventory item %v exceeds the per-type size limit, spilling it to S3", name)
+
+					var ref datauploader.LargePayloadRef
+					if ref, err = p.uploader.UploadLargePayload(p.context, item); err != nil {
+						err = log.Errorf("Item %v exceeds size limit and could not be spilled - %v", name, err.Error())
+						break
+					}
+					item = largePayloadReferenceItem(item, ref)
+				}
+
 				items = append(items, item)
 
-				//return error if collected data breaches size limit
-				if !p.VerifyInventoryDataSize(item, items) {
+				//return error if the reference set still breaches the aggregate size limit
+				if !p.VerifyInventoryDataSize(items) {
 					err = log.Errorf("Size limit exceeded for collected data.")
 					break
 				}
@@ -196,20 +307,31 @@ func (p *Plugin) VerifyAndRunGatherers(policy inventory.Policy) (items []invento
 	return items, err
 }
 
-// VerifyInventoryDataSize returns true if size of collected inventory data is within size restrictions placed by SSM,
-// else false.
-func (p *Plugin) VerifyInventoryDataSize(item inventory.Item, items []inventory.Item) bool {
-	var itemSize, itemsSize float32
-
-	//calculating sizes
-	itemSize = float32(len([]byte(fmt.Sprintf("%s", item))))
-	itemsSize = float32(len([]byte(fmt.Sprintf("%s", items))))
+// itemExceedsPerTypeLimit returns true if item alone breaches SizeLimitKBPerInventoryType.
+func itemExceedsPerTypeLimit(item inventory.Item) bool {
+	itemSize := float32(len([]byte(fmt.Sprintf("%s", item))))
+	return (itemSize / 1000) > inventory.SizeLimitKBPerInventoryType
+}
 
-	if (itemSize/1000) > inventory.SizeLimitKBPerInventoryType || (itemsSize/1000) > inventory.TotalSizeLimitKB {
-		return false
-	} else {
-		return true
+// largePayloadReferenceItem replaces item's content with a small pointer to where the full payload
+// was spilled, so it can stand in for item on the PutInventory call.
+func largePayloadReferenceItem(item inventory.Item, ref datauploader.LargePayloadRef) inventory.Item {
+	item.Content = map[string]interface{}{
+		"Bucket":    ref.Bucket,
+		"Key":       ref.Key,
+		"Sha256":    ref.Sha256,
+		"ItemCount": ref.ItemCount,
+		"Size":      ref.Size,
 	}
+	return item
+}
+
+// VerifyInventoryDataSize returns true if the aggregate size of the collected inventory data (after
+// any individually oversized items have already been replaced with a LargePayloadRef) is within the
+// restriction SSM places on a single PutInventory call, else false.
+func (p *Plugin) VerifyInventoryDataSize(items []inventory.Item) bool {
+	itemsSize := float32(len([]byte(fmt.Sprintf("%s", items))))
+	return (itemsSize / 1000) <= inventory.TotalSizeLimitKB
 }
 
 // ICorePlugin implementation
@@ -219,30 +341,234 @@ func (p *Plugin) Name() string {
 	return inventory.InventoryPluginName
 }
 
-// Execute starts the scheduling of inventory plugin
-func (p *Plugin) Execute(context context.T) (err error) {
+// defaultMaxConcurrency bounds how many gatherers run at once when appconfig doesn't configure one.
+const defaultMaxConcurrency = 5
+
+// defaultLargePayloadRetentionDays is the retention the reaper uses when appconfig doesn't configure
+// one (or configures a non-positive value) - without a floor here, a zero value would have the
+// reaper's first tick delete every spilled object as soon as it's written, defeating the feature.
+const defaultLargePayloadRetentionDays = 30
 
+// scheduleTickInterval is how often Execute checks for gatherers that have come due.
+const scheduleTickInterval = 30 * time.Second
+
+// Execute starts watching the inventory policy source, and starts the per-gatherer scheduler loop
+// that replaced the old single policy-wide job: each gatherer now runs on its own interval, and a
+// slow or failing gatherer no longer delays the rest. Sources that cannot support push notifications
+// (e.g. S3) fall back to polling internally, so this no longer needs its own scheduled job either.
+func (p *Plugin) Execute(context context.T) (err error) {
 	log := context.Log()
 	log.Infof("Starting %v plugin", inventory.InventoryPluginName)
 
-	//Note: Currently this plugin is not integrated with associate plugin so in turn
-	//it schedules a job - that periodically reads inventory policy doc from a file and applies it.
-	//TODO: remove this scheduled job - after integrating with associate plugin
-	if p.isEnabled {
-		if p.job, err = scheduler.Every(p.frequencyInMinutes).Minutes().Run(p.ApplyInventoryPolicy); err != nil {
-			err = log.Errorf("Unable to schedule %v plugin. %v", inventory.InventoryPluginName, err)
-		}
-	} else {
+	if !p.isEnabled {
 		log.Debugf("Skipping execution of %s plugin since its disabled", inventory.InventoryPluginName)
+		return
 	}
+
+	policyEvents, err := p.policySource.Watch(p.context)
+	if err != nil {
+		return log.Errorf("Unable to watch %v plugin policy source. %v", inventory.InventoryPluginName, err)
+	}
+
+	p.stopChan = make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-policyEvents:
+				if !ok {
+					return
+				}
+				if event.Err != nil {
+					log.Errorf("Encountered error while watching inventory policy - %v", event.Err.Error())
+					continue
+				}
+
+				//pick up any custom gatherer .so files that were added, changed or removed since the last run
+				gatherers.Rescan(p.context, p.registeredGatherers)
+				p.publish(events.PolicyLoaded{GathererCount: len(event.Policy.InventoryPolicy)})
+				p.schedule.apply(event.Policy, p.defaultInterval)
+			case <-p.stopChan:
+				return
+			}
+		}
+	}()
+
+	go p.runScheduleLoop()
+
 	return
 }
 
-// RequestStop handles the termination of inventory plugin job
+// runScheduleLoop ticks every scheduleTickInterval, running whichever gatherers have come due and
+// publishing a ScheduleSnapshot so other components can observe per-gatherer health.
+func (p *Plugin) runScheduleLoop() {
+	ticker := time.NewTicker(scheduleTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.runDueGatherers()
+			p.publish(events.ScheduleSnapshot{Gatherers: p.schedule.snapshot()})
+		case <-p.stopChan:
+			return
+		}
+	}
+}
+
+// runDueGatherers runs every gatherer that schedule.dueGatherers returns, bounded to
+// p.maxConcurrency at a time, then coalesces whatever they collected into a single PutInventory
+// call - preserving the same "all-or-nothing per policy" invariant VerifyAndRunGatherers enforces
+// on the manual path: if any due gatherer in this tick errored, or the coalesced batch breaches the
+// aggregate size limit, the whole upload is skipped rather than sending a partial payload.
+// RequestStop stops it from handing out new gatherer runs, but - like runGathererWithTimeout - it
+// cannot cancel a gatherers.T run that is already in flight, so it lets those finish before
+// returning.
+func (p *Plugin) runDueGatherers() {
+	log := p.context.Log()
+
+	due := p.schedule.dueGatherers(time.Now())
+	if len(due) == 0 {
+		return
+	}
+
+	var (
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, p.maxConcurrency)
+		mu     sync.Mutex
+		items  []inventory.Item
+		failed bool
+	)
+
+	for _, st := range due {
+		select {
+		case <-p.stopChan:
+			wg.Wait()
+			return
+		default:
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(st dueGatherer) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			item, err := p.runGatherer(st)
+			p.schedule.recordResult(st.name, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed = true
+				return
+			}
+			items = append(items, item)
+		}(st)
+	}
+
+	wg.Wait()
+
+	if failed {
+		log.Infof("Skipping upload for this tick - at least one due gatherer errored, and we don't send partial inventory data")
+		return
+	}
+
+	if len(items) == 0 {
+		return
+	}
+
+	if !p.VerifyInventoryDataSize(items) {
+		log.Errorf("Size limit exceeded for collected data. Skipping upload for this tick")
+		return
+	}
+
+	p.uploadItems(items)
+}
+
+// runGatherer invokes a single gatherer, bounded by its configured timeout, and spills its result to
+// S3 if it breaches the per-type size limit - the same handling VerifyAndRunGatherers applies on the
+// manual path. st is a snapshot taken by dueGatherers, not a live pointer into schedule's state, so
+// it's safe to read here without s.mu even if a policy update mutates the gatherer concurrently.
+func (p *Plugin) runGatherer(st dueGatherer) (inventory.Item, error) {
+	log := p.context.Log()
+
+	gatherer, isGathererRegistered := p.registeredGatherers.Get(st.name)
+	if !isGathererRegistered {
+		return inventory.Item{}, log.Errorf("Unrecognized inventory gatherer - %v ", st.name)
+	}
+
+	log.Infof("Invoking gatherer - %v", st.name)
+	p.publish(events.GathererStarted{Name: st.name})
+	start := time.Now()
+	item, err := runGathererWithTimeout(p.context, gatherer, st.config, st.timeout)
+	p.publish(events.GathererCompleted{Name: st.name, Item: item, Duration: time.Since(start), Err: err})
+
+	if err != nil {
+		return inventory.Item{}, log.Errorf("Encountered error while executing %v. Error - %v", st.name, err.Error())
+	}
+
+	if itemExceedsPerTypeLimit(item) {
+		p.publish(events.SizeLimitExceeded{Name: st.name})
+		log.Infof("Inventory item %v exceeds the per-type size limit, spilling it to S3", st.name)
+
+		ref, err := p.uploader.UploadLargePayload(p.context, item)
+		if err != nil {
+			return inventory.Item{}, log.Errorf("Item %v exceeds size limit and could not be spilled - %v", st.name, err.Error())
+		}
+		item = largePayloadReferenceItem(item, ref)
+	}
+
+	return item, nil
+}
+
+// runGathererWithTimeout invokes gatherer.Run, bounding it to timeout if one is configured. There is
+// no way to cancel a gatherers.T run that's already in flight, so a timed-out run is left to finish
+// in the background - its result is simply discarded and the timeout is recorded as an error.
+func runGathererWithTimeout(context context.T, gatherer gatherers.T, config inventory.Config, timeout time.Duration) (inventory.Item, error) {
+	if timeout <= 0 {
+		return gatherer.Run(context, config)
+	}
+
+	type result struct {
+		item inventory.Item
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		item, err := gatherer.Run(context, config)
+		done <- result{item, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.item, r.err
+	case <-time.After(timeout):
+		return inventory.Item{}, fmt.Errorf("gatherer %v timed out after %v", gatherer.Name(), timeout)
+	}
+}
+
+// RequestStop handles the termination of inventory plugin job. Safe to call more than once - a
+// repeated call is a no-op instead of panicking on a double close of p.stopChan.
 func (p *Plugin) RequestStop(stopType contracts.StopType) (err error) {
-	if p.job != nil {
-		p.context.Log().Info("Stopping inventory job.")
-		p.job.Quit <- true
+	p.context.Log().Info("Stopping inventory job.")
+
+	p.stopMu.Lock()
+	alreadyStopped := p.stopped
+	p.stopped = true
+	p.stopMu.Unlock()
+
+	if alreadyStopped {
+		return nil
+	}
+
+	if p.stopChan != nil {
+		close(p.stopChan)
+	}
+	if p.reaper != nil {
+		p.reaper.Stop()
 	}
-	return nil
+	return p.policySource.Close()
 }
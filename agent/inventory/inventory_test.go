@@ -0,0 +1,184 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/datauploader"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/gatherers"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/policysource"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// fakeGatherer is a minimal gatherers.T that returns a canned item or error, and counts how many
+// times Run was actually invoked.
+type fakeGatherer struct {
+	name string
+	item inventory.Item
+	err  error
+	runs int32
+}
+
+func (f *fakeGatherer) Run(ctx context.T, config inventory.Config) (inventory.Item, error) {
+	atomic.AddInt32(&f.runs, 1)
+	return f.item, f.err
+}
+
+func (f *fakeGatherer) Name() string { return f.name }
+
+// fakeUploader is a minimal datauploader.T that records whether SendDataToSSM was ever called,
+// instead of actually talking to SSM.
+type fakeUploader struct {
+	mu          sync.Mutex
+	uploadCalls int
+}
+
+func (u *fakeUploader) ConvertToSsmInventoryItems(ctx context.T, items []inventory.Item) ([]*ssm.InventoryItem, error) {
+	return nil, nil
+}
+
+func (u *fakeUploader) SendDataToSSM(ctx context.T, items []*ssm.InventoryItem) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.uploadCalls++
+}
+
+func (u *fakeUploader) UploadLargePayload(ctx context.T, item inventory.Item) (datauploader.LargePayloadRef, error) {
+	return datauploader.LargePayloadRef{}, nil
+}
+
+func (u *fakeUploader) calls() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.uploadCalls
+}
+
+// fakePolicySource is a minimal policysource.T, just enough for RequestStop to have something to
+// Close without talking to a real file, S3 bucket or association document.
+type fakePolicySource struct{}
+
+func (fakePolicySource) Fetch(ctx context.T) (inventory.Policy, policysource.PolicyMeta, error) {
+	return inventory.Policy{}, policysource.PolicyMeta{}, policysource.ErrNoPolicy
+}
+func (fakePolicySource) Watch(ctx context.T) (<-chan policysource.PolicyEvent, error) {
+	return make(chan policysource.PolicyEvent), nil
+}
+func (fakePolicySource) Close() error { return nil }
+
+func newTestPlugin(uploader *fakeUploader, registry *gatherers.Registry) *Plugin {
+	return &Plugin{
+		context:             context.NewMockDefault(),
+		registeredGatherers: registry,
+		uploader:            uploader,
+		policySource:        fakePolicySource{},
+		schedule:            newSchedule(),
+		maxConcurrency:      defaultMaxConcurrency,
+		stopChan:            make(chan struct{}),
+	}
+}
+
+func TestRunDueGatherersSkipsUploadWhenAnyDueGathererErrors(t *testing.T) {
+	registry := gatherers.NewRegistry()
+	registry.Register("ok", &fakeGatherer{name: "ok", item: inventory.Item{Name: "ok", Content: "fine"}})
+	registry.Register("bad", &fakeGatherer{name: "bad", err: errors.New("boom")})
+
+	uploader := &fakeUploader{}
+	p := newTestPlugin(uploader, registry)
+	p.schedule.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{
+		"ok":  {},
+		"bad": {},
+	}}, time.Minute)
+
+	p.runDueGatherers()
+
+	if got := uploader.calls(); got != 0 {
+		t.Fatalf("expected upload to be skipped when a due gatherer errors, got %v calls", got)
+	}
+}
+
+func TestRunDueGatherersSkipsUploadWhenAggregateSizeBreached(t *testing.T) {
+	registry := gatherers.NewRegistry()
+	policy := inventory.Policy{InventoryPolicy: map[string]inventory.Config{}}
+
+	// Eight items of 150KB each (under the 200KB per-type limit, so none of them individually
+	// spills to S3) comfortably breach the 1024KB aggregate limit together.
+	bigContent := strings.Repeat("a", 150*1000)
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("gatherer-%d", i)
+		registry.Register(name, &fakeGatherer{name: name, item: inventory.Item{Name: name, Content: bigContent}})
+		policy.InventoryPolicy[name] = inventory.Config{}
+	}
+
+	uploader := &fakeUploader{}
+	p := newTestPlugin(uploader, registry)
+	p.schedule.apply(policy, time.Minute)
+
+	p.runDueGatherers()
+
+	if got := uploader.calls(); got != 0 {
+		t.Fatalf("expected upload to be skipped when the aggregate size limit is breached, got %v calls", got)
+	}
+}
+
+func TestRunDueGatherersUploadsWhenEverythingSucceeds(t *testing.T) {
+	registry := gatherers.NewRegistry()
+	registry.Register("ok", &fakeGatherer{name: "ok", item: inventory.Item{Name: "ok", Content: "fine"}})
+
+	uploader := &fakeUploader{}
+	p := newTestPlugin(uploader, registry)
+	p.schedule.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{"ok": {}}}, time.Minute)
+
+	p.runDueGatherers()
+
+	if got := uploader.calls(); got != 1 {
+		t.Fatalf("expected a single upload when every due gatherer succeeds, got %v calls", got)
+	}
+}
+
+func TestRequestStopStopsDispatchingNewGathererRuns(t *testing.T) {
+	registry := gatherers.NewRegistry()
+	gatherer := &fakeGatherer{name: "g", item: inventory.Item{Name: "g", Content: "fine"}}
+	registry.Register(gatherer.name, gatherer)
+
+	uploader := &fakeUploader{}
+	p := newTestPlugin(uploader, registry)
+	p.schedule.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{"g": {}}}, time.Minute)
+
+	if err := p.RequestStop(contracts.StopTypeSoftStop); err != nil {
+		t.Fatalf("RequestStop returned error: %v", err)
+	}
+
+	// A second RequestStop must not panic on a double close of stopChan.
+	if err := p.RequestStop(contracts.StopTypeSoftStop); err != nil {
+		t.Fatalf("second RequestStop returned error: %v", err)
+	}
+
+	p.runDueGatherers()
+
+	if atomic.LoadInt32(&gatherer.runs) != 0 {
+		t.Fatalf("expected no gatherer runs to be dispatched after RequestStop, got %v", gatherer.runs)
+	}
+	if got := uploader.calls(); got != 0 {
+		t.Fatalf("expected no upload to be dispatched after RequestStop, got %v calls", got)
+	}
+}
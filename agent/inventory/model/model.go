@@ -0,0 +1,67 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package model holds the data types shared across the inventory plugin, its gatherers, policy
+// sources and uploader. It is declared as package inventory (rather than model) so callers can
+// write the more natural inventory.Policy, inventory.Item, etc.
+package inventory
+
+import "time"
+
+const (
+	// InventoryPluginName is the name the inventory core plugin registers itself under.
+	InventoryPluginName = "aws:softwareInventory"
+
+	// Enabled is the appconfig.SsmagentConfig.Ssm.InventoryPlugin value that turns the plugin on.
+	Enabled = "Enabled"
+
+	// ErrorThreshold is how many consecutive errors trip the inventory plugin's stop policy.
+	ErrorThreshold = 10
+
+	// InventoryPolicyDocName is the file name of the inventory policy document within its folder.
+	InventoryPolicyDocName = "inventory.json"
+
+	// SizeLimitKBPerInventoryType is the largest a single inventory item may be, in KB, before it
+	// must be spilled out of band rather than sent directly to PutInventory.
+	SizeLimitKBPerInventoryType float32 = 200
+
+	// TotalSizeLimitKB is the largest the aggregate of one PutInventory call's items may be, in KB.
+	TotalSizeLimitKB float32 = 1024
+)
+
+// Config is a single gatherer's configuration within an inventory Policy.
+type Config struct {
+	Collection string                 `json:"Collection"`
+	Properties map[string]interface{} `json:"Properties"`
+
+	// IntervalMinutes overrides how often this gatherer runs; 0 means "use the plugin-wide default".
+	IntervalMinutes int `json:"IntervalMinutes,omitempty"`
+	// Timeout bounds how long a single run of this gatherer may take; 0 means "no timeout".
+	Timeout time.Duration `json:"Timeout,omitempty"`
+	// Priority orders gatherers competing for a shared worker pool slot - lower runs first.
+	Priority int `json:"Priority,omitempty"`
+}
+
+// Policy is the inventory policy document - one Config per gatherer name.
+type Policy struct {
+	InventoryPolicy map[string]Config `json:"InventoryPolicy"`
+}
+
+// Item is a single gatherer's collected inventory data, ready to be converted to SSM's wire format
+// or spilled to a LargePayloadStore.
+type Item struct {
+	Name          string
+	Content       interface{}
+	SchemaVersion string
+	CaptureTime   string
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package policysource
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+	messageContracts "github.com/aws/amazon-ssm-agent/agent/message/contracts"
+)
+
+// AssociationPolicySource lets the inventory plugin be driven by an association document instead
+// of a sidecar policy file. It holds no document of its own - the association plugin calls Update
+// with each document it parses via parser.ParseDocumentWithParams, and this source extracts the
+// configuration of the plugin named pluginName (if present) and surfaces it as an inventory.Policy.
+type AssociationPolicySource struct {
+	pluginName string
+
+	mu      sync.Mutex
+	current inventory.Policy
+	meta    PolicyMeta
+	hasData bool
+	closed  bool
+
+	events chan PolicyEvent
+}
+
+// NewAssociationPolicySource creates an AssociationPolicySource that looks for a plugin named
+// pluginName in documents passed to Update.
+func NewAssociationPolicySource(pluginName string) *AssociationPolicySource {
+	return &AssociationPolicySource{pluginName: pluginName, events: make(chan PolicyEvent, 1)}
+}
+
+// Update is called by the association plugin whenever it parses a document, so this source can
+// pick up an updated inventory policy from it. Documents that don't configure pluginName are
+// ignored.
+func (s *AssociationPolicySource) Update(payload *messageContracts.SendCommandPayload) {
+	config, ok := payload.DocumentContent.RuntimeConfig[s.pluginName]
+	if !ok {
+		return
+	}
+
+	raw, err := json.Marshal(config.Properties)
+	if err != nil {
+		return
+	}
+
+	var policy inventory.Policy
+	if err = json.Unmarshal(raw, &policy); err != nil {
+		return
+	}
+
+	meta := PolicyMeta{SourceName: "association", Version: payload.CommandID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current, s.meta, s.hasData = policy, meta, true
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.events <- PolicyEvent{Policy: policy, Meta: meta}:
+	default:
+		// a previous update is still waiting to be consumed; Fetch will pick up the latest state.
+	}
+}
+
+// Fetch implements T.
+func (s *AssociationPolicySource) Fetch(ctx context.T) (inventory.Policy, PolicyMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasData {
+		return inventory.Policy{}, PolicyMeta{}, ErrNoPolicy
+	}
+	return s.current, s.meta, nil
+}
+
+// Watch implements T.
+func (s *AssociationPolicySource) Watch(ctx context.T) (<-chan PolicyEvent, error) {
+	return s.events, nil
+}
+
+// Close implements T. It closes the channel returned by Watch so CompositeSource's fan-in
+// goroutine for this source returns instead of blocking forever on a channel nobody will ever
+// send to or close again.
+func (s *AssociationPolicySource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.events)
+	return nil
+}
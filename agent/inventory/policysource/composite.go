@@ -0,0 +1,108 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package policysource
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+)
+
+// CompositeSource composes an ordered list of sources behind a single T: Fetch tries each source
+// in order and returns the first one with a non-empty policy, and Watch keeps all of them live but
+// re-applies that same priority rule to every event instead of forwarding whichever source fired.
+type CompositeSource struct {
+	sources []T
+}
+
+// NewCompositeSource composes sources, in priority order.
+func NewCompositeSource(sources ...T) *CompositeSource {
+	return &CompositeSource{sources: sources}
+}
+
+// Fetch implements T. It returns the first source's policy that isn't empty, so that e.g. an
+// association document always wins over a stale local file once it defines any gatherers.
+func (c *CompositeSource) Fetch(ctx context.T) (inventory.Policy, PolicyMeta, error) {
+	var lastErr error
+
+	for _, source := range c.sources {
+		policy, meta, err := source.Fetch(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(policy.InventoryPolicy) == 0 {
+			continue
+		}
+		return policy, meta, nil
+	}
+
+	if lastErr == nil {
+		lastErr = ErrNoPolicy
+	}
+	return inventory.Policy{}, PolicyMeta{}, lastErr
+}
+
+// Watch implements T by fanning in every source's Watch channel, but never forwards a source's
+// event as-is: whichever source fired, the composite re-Fetches across all of them and emits the
+// result, so a lower-priority source's change can never overwrite an already-active higher-priority
+// policy on the live path the way forwarding the raw event would.
+func (c *CompositeSource) Watch(ctx context.T) (<-chan PolicyEvent, error) {
+	out := make(chan PolicyEvent, 1)
+	var wg sync.WaitGroup
+
+	for _, source := range c.sources {
+		ch, err := source.Watch(ctx)
+		if err != nil {
+			ctx.Log().Errorf("Unable to watch inventory policy source - %v", err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(ch <-chan PolicyEvent) {
+			defer wg.Done()
+			for event := range ch {
+				if event.Err != nil {
+					out <- event
+					continue
+				}
+
+				policy, meta, err := c.Fetch(ctx)
+				if err == ErrNoPolicy {
+					continue
+				}
+				out <- PolicyEvent{Policy: policy, Meta: meta, Err: err}
+			}
+		}(ch)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// Close implements T, closing every composed source and returning the first error encountered.
+func (c *CompositeSource) Close() error {
+	var firstErr error
+	for _, source := range c.sources {
+		if err := source.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
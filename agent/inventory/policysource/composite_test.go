@@ -0,0 +1,149 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package policysource
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+)
+
+// fakeSource is a minimal T used to exercise CompositeSource's priority rules without touching a
+// real file, S3 bucket or association document.
+type fakeSource struct {
+	name   string
+	policy inventory.Policy
+	err    error
+	events chan PolicyEvent
+}
+
+func newFakeSource(name string) *fakeSource {
+	return &fakeSource{name: name, events: make(chan PolicyEvent, 1)}
+}
+
+func (f *fakeSource) Fetch(ctx context.T) (inventory.Policy, PolicyMeta, error) {
+	if f.err != nil {
+		return inventory.Policy{}, PolicyMeta{}, f.err
+	}
+	return f.policy, PolicyMeta{SourceName: f.name}, nil
+}
+
+func (f *fakeSource) Watch(ctx context.T) (<-chan PolicyEvent, error) {
+	return f.events, nil
+}
+
+func (f *fakeSource) Close() error {
+	close(f.events)
+	return nil
+}
+
+func policyWith(gatherer string) inventory.Policy {
+	return inventory.Policy{InventoryPolicy: map[string]inventory.Config{gatherer: {}}}
+}
+
+func TestCompositeSourceFetchPrefersFirstNonEmptyPolicy(t *testing.T) {
+	empty := newFakeSource("file")
+	populated := newFakeSource("association")
+	populated.policy = policyWith("network")
+
+	composite := NewCompositeSource(empty, populated)
+
+	policy, meta, err := composite.Fetch(nil)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if meta.SourceName != "association" {
+		t.Errorf("expected policy from association source, got %v", meta.SourceName)
+	}
+	if _, ok := policy.InventoryPolicy["network"]; !ok {
+		t.Errorf("expected policy to contain the association source's gatherer")
+	}
+}
+
+func TestCompositeSourceFetchFirstSourceInOrderWinsWhenBothPopulated(t *testing.T) {
+	low := newFakeSource("file")
+	low.policy = policyWith("file-gatherer")
+	high := newFakeSource("association")
+	high.policy = policyWith("association-gatherer")
+
+	composite := NewCompositeSource(low, high)
+
+	_, meta, err := composite.Fetch(nil)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if meta.SourceName != "file" {
+		t.Errorf("expected the first source in priority order to win, got %v", meta.SourceName)
+	}
+}
+
+func TestCompositeSourceFetchPrefersAssociationOverFileWhenBothPopulated(t *testing.T) {
+	// Mirrors the priority order Plugin.NewPlugin composes its sources in: association ahead of
+	// file, so a pre-existing sidecar inventory.json never shadows an association-driven policy.
+	association := newFakeSource("association")
+	association.policy = policyWith("association-gatherer")
+	file := newFakeSource("file")
+	file.policy = policyWith("file-gatherer")
+
+	composite := NewCompositeSource(association, file)
+
+	_, meta, err := composite.Fetch(nil)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if meta.SourceName != "association" {
+		t.Errorf("expected the association source to win over file, got %v", meta.SourceName)
+	}
+}
+
+func TestCompositeSourceFetchReturnsErrNoPolicyWhenAllEmpty(t *testing.T) {
+	composite := NewCompositeSource(newFakeSource("file"), newFakeSource("association"))
+
+	_, _, err := composite.Fetch(nil)
+	if err != ErrNoPolicy {
+		t.Errorf("expected ErrNoPolicy, got %v", err)
+	}
+}
+
+func TestCompositeSourceWatchReappliesPriorityInsteadOfForwardingRawEvent(t *testing.T) {
+	// "high" is first in priority order, so it always wins Fetch as long as it has a policy.
+	high := newFakeSource("association")
+	high.policy = policyWith("association-gatherer")
+	low := newFakeSource("file")
+	low.policy = policyWith("file-gatherer")
+
+	composite := NewCompositeSource(high, low)
+
+	events, err := composite.Watch(nil)
+	if err != nil {
+		t.Fatalf("Watch returned error: %v", err)
+	}
+
+	// The lower-priority source fires a change event, but the already-active higher-priority
+	// policy must win on the emitted event rather than the raw low-priority payload being
+	// forwarded as-is.
+	low.events <- PolicyEvent{Policy: low.policy, Meta: PolicyMeta{SourceName: "file"}}
+
+	event := <-events
+	if event.Meta.SourceName != "association" {
+		t.Fatalf("composite should have re-Fetched and kept the higher-priority source's policy, got %v", event.Meta.SourceName)
+	}
+	if _, ok := event.Policy.InventoryPolicy["association-gatherer"]; !ok {
+		t.Errorf("expected the re-fetched higher-priority policy, not the raw forwarded low-priority event")
+	}
+
+	low.Close()
+	high.Close()
+}
@@ -0,0 +1,180 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package policysource
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+	"gopkg.in/fsnotify.v1"
+)
+
+// FilePolicySource reads the inventory policy from a single JSON file on disk - this is the
+// original (and still default) behaviour. It prefers fsnotify for change notification and falls
+// back to polling the file's mtime if a watcher cannot be established, e.g. on a filesystem that
+// doesn't support inotify.
+type FilePolicySource struct {
+	dir          string
+	docName      string
+	pollInterval time.Duration
+
+	watcher *fsnotify.Watcher
+	stop    chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewFilePolicySource creates a FilePolicySource that reads dir/docName, polling at pollInterval
+// when fsnotify isn't available.
+func NewFilePolicySource(dir, docName string, pollInterval time.Duration) *FilePolicySource {
+	return &FilePolicySource{dir: dir, docName: docName, pollInterval: pollInterval, stop: make(chan struct{})}
+}
+
+func (s *FilePolicySource) path() string {
+	return path.Join(s.dir, s.docName)
+}
+
+// Fetch implements T.
+func (s *FilePolicySource) Fetch(ctx context.T) (inventory.Policy, PolicyMeta, error) {
+	var policy inventory.Policy
+	doc := s.path()
+
+	if !fileutil.Exists(doc) {
+		return policy, PolicyMeta{}, ErrNoPolicy
+	}
+
+	info, err := os.Stat(doc)
+	if err != nil {
+		return policy, PolicyMeta{}, err
+	}
+
+	content, err := fileutil.ReadAllText(doc)
+	if err != nil {
+		return policy, PolicyMeta{}, err
+	}
+
+	if err = json.Unmarshal([]byte(content), &policy); err != nil {
+		return policy, PolicyMeta{}, err
+	}
+
+	return policy, PolicyMeta{SourceName: "file", Version: info.ModTime().String()}, nil
+}
+
+// Watch implements T. It emits a PolicyEvent whenever the policy file is created, written or
+// removed, falling back to polling s.path()'s mtime every pollInterval if the fsnotify watcher
+// cannot be created.
+func (s *FilePolicySource) Watch(ctx context.T) (<-chan PolicyEvent, error) {
+	log := ctx.Log()
+	out := make(chan PolicyEvent, 1)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorf("Unable to create file watcher for inventory policy, falling back to polling - %v", err)
+		go s.pollLoop(ctx, out)
+		return out, nil
+	}
+
+	if err = watcher.Add(s.dir); err != nil {
+		watcher.Close()
+		log.Errorf("Unable to watch %v for inventory policy changes, falling back to polling - %v", s.dir, err)
+		go s.pollLoop(ctx, out)
+		return out, nil
+	}
+
+	s.watcher = watcher
+
+	go func() {
+		defer close(out)
+
+		//fsnotify only tells us about future changes - emit whatever is on disk right now too, so a
+		//policy file that already existed before Watch was called (the common case across an agent
+		//restart) is picked up immediately instead of waiting for it to be rewritten.
+		if policy, meta, err := s.Fetch(ctx); err != ErrNoPolicy {
+			out <- PolicyEvent{Policy: policy, Meta: meta, Err: err}
+		}
+
+		for {
+			select {
+			case fsEvent, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(fsEvent.Name) != s.docName {
+					continue
+				}
+				policy, meta, err := s.Fetch(ctx)
+				out <- PolicyEvent{Policy: policy, Meta: meta, Err: err}
+			case watchErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				out <- PolicyEvent{Err: watchErr}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// pollLoop is the fsnotify fallback - it re-reads the policy file every pollInterval and only
+// emits an event when its mtime has actually changed or when reading or parsing it fails.
+func (s *FilePolicySource) pollLoop(ctx context.T, out chan<- PolicyEvent) {
+	defer close(out)
+
+	var lastVersion string
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			policy, meta, err := s.Fetch(ctx)
+			if err == ErrNoPolicy {
+				continue
+			}
+			if err != nil || meta.Version != lastVersion {
+				lastVersion = meta.Version
+				out <- PolicyEvent{Policy: policy, Meta: meta, Err: err}
+			}
+		}
+	}
+}
+
+// Close implements T. Safe to call more than once - a repeated call is a no-op instead of
+// panicking on a double close of s.stop.
+func (s *FilePolicySource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	close(s.stop)
+	if s.watcher != nil {
+		return s.watcher.Close()
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package policysource decouples the inventory plugin from how it discovers its policy document -
+// a local file, an S3 object, or an association document - behind a single interface.
+package policysource
+
+import (
+	"errors"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+)
+
+// ErrNoPolicy is returned by Fetch when the source has no policy document configured, e.g. the
+// policy file does not exist yet.
+var ErrNoPolicy = errors.New("policysource: no policy configured")
+
+// ErrWatchUnsupported signals that a source can only be observed via periodic Fetch. None of the
+// sources in this package return it: each one that lacks notifications polls inside Watch instead.
+var ErrWatchUnsupported = errors.New("policysource: watch not supported by this source")
+
+// PolicyMeta carries bookkeeping about where a Policy came from, so callers can tell whether a
+// later Fetch actually changed anything without re-parsing the policy itself.
+type PolicyMeta struct {
+	// SourceName identifies which PolicySource produced the policy, e.g. "file", "s3", "association".
+	SourceName string
+	// Version is an opaque change-detection token - mtime for a file, ETag for S3, command ID for
+	// an association.
+	Version string
+}
+
+// PolicyEvent is delivered on the channel returned by T.Watch whenever the policy changes, or when
+// watching the source itself fails.
+type PolicyEvent struct {
+	Policy inventory.Policy
+	Meta   PolicyMeta
+	Err    error
+}
+
+// T is implemented by anything capable of supplying an inventory policy document.
+type T interface {
+	// Fetch returns the current policy, or ErrNoPolicy if none is configured on this source.
+	Fetch(context context.T) (inventory.Policy, PolicyMeta, error)
+
+	// Watch returns a channel of PolicyEvent delivered whenever the policy changes. Sources that
+	// cannot support push notifications fall back to polling internally rather than returning
+	// ErrWatchUnsupported, so that callers don't need source-specific fallback logic.
+	Watch(context context.T) (<-chan PolicyEvent, error)
+
+	// Close releases any resources (file watchers, tickers, polling goroutines) held by Watch.
+	Close() error
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package policysource
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// s3PollInterval is how often S3PolicySource checks the object's ETag for Watch - S3 has no push
+// notification cheap enough to hold open per-instance, so this is poll-only.
+const s3PollInterval = 5 * time.Minute
+
+// S3PolicySource reads the inventory policy from a single JSON object in S3 and uses the object's
+// ETag to detect changes.
+type S3PolicySource struct {
+	client *s3.S3
+	bucket string
+	key    string
+
+	stop chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewS3PolicySource creates an S3PolicySource backed by client for s3://bucket/key.
+func NewS3PolicySource(client *s3.S3, bucket, key string) *S3PolicySource {
+	return &S3PolicySource{client: client, bucket: bucket, key: key, stop: make(chan struct{})}
+}
+
+// Fetch implements T.
+func (s *S3PolicySource) Fetch(ctx context.T) (inventory.Policy, PolicyMeta, error) {
+	var policy inventory.Policy
+
+	out, err := s.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key)})
+	if err != nil {
+		return policy, PolicyMeta{}, err
+	}
+	defer out.Body.Close()
+
+	content, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return policy, PolicyMeta{}, err
+	}
+
+	if err = json.Unmarshal(content, &policy); err != nil {
+		return policy, PolicyMeta{}, err
+	}
+
+	meta := PolicyMeta{SourceName: "s3"}
+	if out.ETag != nil {
+		meta.Version = *out.ETag
+	}
+
+	return policy, meta, nil
+}
+
+// Watch implements T by polling the object's ETag every s3PollInterval and only emitting an event
+// when it changes.
+func (s *S3PolicySource) Watch(ctx context.T) (<-chan PolicyEvent, error) {
+	log := ctx.Log()
+	out := make(chan PolicyEvent, 1)
+
+	go func() {
+		defer close(out)
+
+		var lastVersion string
+		ticker := time.NewTicker(s3PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				policy, meta, err := s.Fetch(ctx)
+				if err != nil {
+					log.Errorf("Unable to fetch inventory policy from s3://%v/%v - %v", s.bucket, s.key, err)
+					continue
+				}
+				if meta.Version == lastVersion {
+					continue
+				}
+				lastVersion = meta.Version
+				out <- PolicyEvent{Policy: policy, Meta: meta}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Close implements T. Safe to call more than once - a repeated call is a no-op instead of
+// panicking on a double close of s.stop.
+func (s *S3PolicySource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	close(s.stop)
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package inventory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/inventory/events"
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+)
+
+// maxGathererBackoff caps how far a repeatedly failing gatherer's nextRun can be pushed out.
+const maxGathererBackoff = 30 * time.Minute
+
+// gathererState is one gatherer's scheduling state, derived from the active Policy plus its run
+// history. stopPolicy trips the circuit breaker after ErrorThreshold consecutive errors; the
+// scheduler skips a gatherer entirely while its breaker is open.
+type gathererState struct {
+	name     string
+	config   inventory.Config
+	interval time.Duration
+	timeout  time.Duration
+
+	stopPolicy        *sdkutil.StopPolicy
+	consecutiveErrors int
+	nextRun           time.Time
+}
+
+// schedule tracks per-gatherer scheduling state for a long-running Plugin. It is safe for
+// concurrent use - apply() is called from the policy Watch goroutine while dueGatherers() and
+// recordResult() are called from the scheduler tick goroutine.
+type schedule struct {
+	mu     sync.Mutex
+	states map[string]*gathererState
+}
+
+func newSchedule() *schedule {
+	return &schedule{states: make(map[string]*gathererState)}
+}
+
+// apply updates per-gatherer state from policy. Gatherers new to policy are scheduled to run on
+// the next tick; gatherers no longer present are dropped. Existing gatherers keep their circuit
+// breaker and backoff state across a policy change.
+func (s *schedule) apply(policy inventory.Policy, defaultInterval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool, len(policy.InventoryPolicy))
+	for name, config := range policy.InventoryPolicy {
+		seen[name] = true
+
+		interval := defaultInterval
+		if config.IntervalMinutes > 0 {
+			interval = time.Duration(config.IntervalMinutes) * time.Minute
+		}
+
+		if st, ok := s.states[name]; ok {
+			st.config, st.interval, st.timeout = config, interval, config.Timeout
+			continue
+		}
+
+		s.states[name] = &gathererState{
+			name:       name,
+			config:     config,
+			interval:   interval,
+			timeout:    config.Timeout,
+			stopPolicy: sdkutil.NewStopPolicy(name, inventory.ErrorThreshold),
+			nextRun:    time.Now(),
+		}
+	}
+
+	for name := range s.states {
+		if !seen[name] {
+			delete(s.states, name)
+		}
+	}
+}
+
+// dueGatherer is a snapshot of the fields of a gathererState a gatherer run needs, taken under
+// s.mu so the caller can read them after dueGatherers returns without racing apply(), which
+// mutates the live *gathererState in place on a policy update.
+type dueGatherer struct {
+	name    string
+	config  inventory.Config
+	timeout time.Duration
+}
+
+// dueGatherers returns a snapshot of every gatherer whose nextRun has passed and whose circuit
+// breaker is closed, ordered by Priority (lower first).
+func (s *schedule) dueGatherers(now time.Time) []dueGatherer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []dueGatherer
+	for _, st := range s.states {
+		if st.nextRun.After(now) {
+			continue
+		}
+		if !st.stopPolicy.IsHealthy() {
+			continue
+		}
+		due = append(due, dueGatherer{name: st.name, config: st.config, timeout: st.timeout})
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].config.Priority < due[j].config.Priority })
+	return due
+}
+
+// recordResult updates a gatherer's circuit breaker and schedules its next run. A failure backs
+// off exponentially (capped at maxGathererBackoff); a success resets the backoff and schedules the
+// gatherer at its normal interval.
+func (s *schedule) recordResult(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.states[name]
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		st.stopPolicy.AddErrorCount(1)
+		st.consecutiveErrors++
+
+		backoff := st.interval << uint(minInt(st.consecutiveErrors, 5))
+		if backoff > maxGathererBackoff {
+			backoff = maxGathererBackoff
+		}
+		st.nextRun = time.Now().Add(backoff)
+		return
+	}
+
+	st.stopPolicy.ResetErrorCount()
+	st.consecutiveErrors = 0
+	st.nextRun = time.Now().Add(st.interval)
+}
+
+// snapshot returns the current scheduling state of every gatherer, for publishing on the event bus.
+func (s *schedule) snapshot() []events.GathererSchedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]events.GathererSchedule, 0, len(s.states))
+	for _, st := range s.states {
+		out = append(out, events.GathererSchedule{
+			Name:              st.name,
+			NextRun:           st.nextRun,
+			ConsecutiveErrors: st.consecutiveErrors,
+			Healthy:           st.stopPolicy.IsHealthy(),
+		})
+	}
+	return out
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
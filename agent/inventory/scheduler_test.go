@@ -0,0 +1,163 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/inventory/model"
+)
+
+func TestScheduleApplyDropsGatherersNoLongerInPolicyButKeepsExistingState(t *testing.T) {
+	s := newSchedule()
+	s.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{
+		"network": {},
+		"disk":    {},
+	}}, time.Minute)
+
+	// give "disk" some history, then re-apply a policy that drops it and keeps "network".
+	s.recordResult("disk", assertErr)
+	s.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{
+		"network": {},
+	}}, time.Minute)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.states["disk"]; ok {
+		t.Error("expected \"disk\" to be dropped once it left the policy")
+	}
+	if _, ok := s.states["network"]; !ok {
+		t.Error("expected \"network\" to remain scheduled")
+	}
+}
+
+func TestScheduleApplyKeepsCircuitBreakerStateAcrossReapply(t *testing.T) {
+	s := newSchedule()
+	policy := inventory.Policy{InventoryPolicy: map[string]inventory.Config{"network": {}}}
+	s.apply(policy, time.Minute)
+
+	s.recordResult("network", assertErr)
+	s.recordResult("network", assertErr)
+
+	s.apply(policy, time.Minute)
+
+	s.mu.Lock()
+	errs := s.states["network"].consecutiveErrors
+	s.mu.Unlock()
+	if errs != 2 {
+		t.Errorf("expected consecutiveErrors to survive a reapply of the same policy, got %v", errs)
+	}
+}
+
+func TestScheduleDueGatherersOrdersByPriorityAndSkipsNotYetDue(t *testing.T) {
+	s := newSchedule()
+	s.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{
+		"low":  {Priority: 10},
+		"high": {Priority: 1},
+	}}, time.Minute)
+
+	// push "low" out into the future so only "high" is due.
+	s.mu.Lock()
+	s.states["low"].nextRun = time.Now().Add(time.Hour)
+	s.mu.Unlock()
+
+	due := s.dueGatherers(time.Now())
+	if len(due) != 1 || due[0].name != "high" {
+		t.Fatalf("expected only \"high\" to be due, got %v", namesOf(due))
+	}
+
+	s.mu.Lock()
+	s.states["low"].nextRun = time.Now().Add(-time.Minute)
+	s.mu.Unlock()
+
+	due = s.dueGatherers(time.Now())
+	if len(due) != 2 || due[0].name != "high" || due[1].name != "low" {
+		t.Fatalf("expected [high, low] ordered by priority, got %v", namesOf(due))
+	}
+}
+
+func TestScheduleDueGatherersSkipsTrippedCircuitBreaker(t *testing.T) {
+	s := newSchedule()
+	s.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{"network": {}}}, time.Minute)
+
+	for i := 0; i < inventory.ErrorThreshold; i++ {
+		s.recordResult("network", assertErr)
+	}
+
+	due := s.dueGatherers(time.Now())
+	if len(due) != 0 {
+		t.Fatalf("expected a tripped circuit breaker to make the gatherer skip due scheduling, got %v", namesOf(due))
+	}
+}
+
+func TestScheduleRecordResultBacksOffExponentiallyAndCapsAtMax(t *testing.T) {
+	s := newSchedule()
+	s.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{"network": {}}}, time.Minute)
+
+	var lastDelay time.Duration
+	for i := 0; i < 10; i++ {
+		before := time.Now()
+		s.recordResult("network", assertErr)
+
+		s.mu.Lock()
+		delay := s.states["network"].nextRun.Sub(before)
+		s.mu.Unlock()
+
+		if delay > maxGathererBackoff+time.Second {
+			t.Fatalf("backoff exceeded the cap: %v", delay)
+		}
+		if i > 0 && i < 5 && delay <= lastDelay {
+			t.Errorf("expected backoff to grow on consecutive failures, got %v after %v", delay, lastDelay)
+		}
+		lastDelay = delay
+	}
+}
+
+func TestScheduleRecordResultOnSuccessResetsBackoffToNormalInterval(t *testing.T) {
+	s := newSchedule()
+	s.apply(inventory.Policy{InventoryPolicy: map[string]inventory.Config{"network": {}}}, 5*time.Minute)
+
+	s.recordResult("network", assertErr)
+	s.recordResult("network", assertErr)
+	s.recordResult("network", nil)
+
+	s.mu.Lock()
+	st := s.states["network"]
+	errs := st.consecutiveErrors
+	nextRun := st.nextRun
+	s.mu.Unlock()
+
+	if errs != 0 {
+		t.Errorf("expected a success to reset consecutiveErrors, got %v", errs)
+	}
+	wantAround := time.Now().Add(5 * time.Minute)
+	if nextRun.Before(wantAround.Add(-time.Second)) || nextRun.After(wantAround.Add(time.Second)) {
+		t.Errorf("expected nextRun to be scheduled at the normal interval after a success, got %v want around %v", nextRun, wantAround)
+	}
+}
+
+var assertErr = &testSchedulerError{}
+
+type testSchedulerError struct{}
+
+func (*testSchedulerError) Error() string { return "simulated gatherer failure" }
+
+func namesOf(states []dueGatherer) []string {
+	names := make([]string, len(states))
+	for i, st := range states {
+		names[i] = st.name
+	}
+	return names
+}